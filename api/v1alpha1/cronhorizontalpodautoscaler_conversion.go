@@ -0,0 +1,280 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+// conversionDataAnnotation is the annotation to keep the v1beta1 spec fields which v1alpha1 cannot represent.
+const conversionDataAnnotation = "cron-hpa.dtaniwaki.github.com/conversion-data"
+
+// conversionData is the v1beta1 spec without the fields which v1alpha1 represents.
+// The status is not kept because the status written in v1alpha1 is ignored except by the status subresource,
+// which only the controller writes in v1beta1.
+type conversionData struct {
+	Spec       v1beta1.CronHorizontalPodAutoscalerSpec `json:"spec"`
+	NoTemplate bool                                    `json:"noTemplate,omitempty"`
+}
+
+// ConvertTo converts this CronHorizontalPodAutoscaler to the Hub version (v1beta1).
+func (src *CronHorizontalPodAutoscaler) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.CronHorizontalPodAutoscaler)
+
+	dst.ObjectMeta = src.ObjectMeta
+	convertSpecToV1beta1(&src.Spec, &dst.Spec)
+	convertStatusToV1beta1(&src.Status, &dst.Status)
+
+	restored, err := unmarshalConversionData(&dst.ObjectMeta)
+	if err != nil {
+		return err
+	}
+	if restored != nil {
+		restoreSpec(restored, &dst.Spec)
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1beta1) to this version.
+func (dst *CronHorizontalPodAutoscaler) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.CronHorizontalPodAutoscaler)
+
+	dst.ObjectMeta = src.ObjectMeta
+	convertSpecFromV1beta1(&src.Spec, &dst.Spec)
+	convertStatusFromV1beta1(&src.Status, &dst.Status)
+
+	return marshalConversionData(&src.Spec, &dst.ObjectMeta)
+}
+
+// marshalConversionData keeps the spec fields of the hub which v1alpha1 cannot represent in the annotation.
+// No annotation is added if v1alpha1 represents the whole spec.
+func marshalConversionData(src *v1beta1.CronHorizontalPodAutoscalerSpec, dst *metav1.ObjectMeta) error {
+	data := newConversionData(src)
+	if data == nil {
+		return nil
+	}
+	value, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	annotations := make(map[string]string, len(dst.Annotations)+1)
+	for key, value := range dst.Annotations {
+		annotations[key] = value
+	}
+	annotations[conversionDataAnnotation] = string(value)
+	dst.Annotations = annotations
+	return nil
+}
+
+// newConversionData clears the fields which v1alpha1 represents from the spec, and drops the scheduled patches
+// with nothing left but their names. It returns nil if nothing is left.
+func newConversionData(src *v1beta1.CronHorizontalPodAutoscalerSpec) *conversionData {
+	data := &conversionData{Spec: *src.DeepCopy(), NoTemplate: src.Template == nil}
+	data.Spec.Template = nil
+	data.Spec.ScheduledPatches = nil
+	for _, scheduledPatch := range src.ScheduledPatches {
+		scheduledPatch := *scheduledPatch.DeepCopy()
+		scheduledPatch.Schedule = ""
+		scheduledPatch.Timezone = ""
+		if patch := scheduledPatch.Patch; patch != nil {
+			if !isRelativeReplicas(patch.MinReplicas) {
+				patch.MinReplicas = nil
+			}
+			if !isRelativeReplicas(patch.MaxReplicas) {
+				patch.MaxReplicas = nil
+			}
+			patch.Metrics = nil
+			if reflect.DeepEqual(*patch, v1beta1.HPAPatch{}) {
+				scheduledPatch.Patch = nil
+			}
+		}
+		if !reflect.DeepEqual(scheduledPatch, v1beta1.CronHorizontalPodAutoscalerScheduledPatch{Name: scheduledPatch.Name}) {
+			data.Spec.ScheduledPatches = append(data.Spec.ScheduledPatches, scheduledPatch)
+		}
+	}
+	if reflect.DeepEqual(*data, conversionData{}) {
+		return nil
+	}
+	return data
+}
+
+// unmarshalConversionData takes the data kept by marshalConversionData out of the annotations.
+func unmarshalConversionData(dst *metav1.ObjectMeta) (*conversionData, error) {
+	value, ok := dst.Annotations[conversionDataAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	annotations := make(map[string]string, len(dst.Annotations))
+	for key, value := range dst.Annotations {
+		if key != conversionDataAnnotation {
+			annotations[key] = value
+		}
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	dst.Annotations = annotations
+
+	restored := &conversionData{}
+	if err := json.Unmarshal([]byte(value), restored); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// restoreSpec restores the v1beta1 fields which v1alpha1 cannot represent on top of the fields converted from v1alpha1.
+// The scheduled patches are matched by name because they can be modified in v1alpha1.
+func restoreSpec(restored *conversionData, dst *v1beta1.CronHorizontalPodAutoscalerSpec) {
+	converted := *dst
+	*dst = restored.Spec
+	dst.Template = converted.Template
+	if restored.NoTemplate {
+		dst.Template = nil
+	}
+	dst.ScheduledPatches = converted.ScheduledPatches
+	restoredPatches := make(map[string]*v1beta1.CronHorizontalPodAutoscalerScheduledPatch, len(restored.Spec.ScheduledPatches))
+	for i := range restored.Spec.ScheduledPatches {
+		restoredPatches[restored.Spec.ScheduledPatches[i].Name] = &restored.Spec.ScheduledPatches[i]
+	}
+	for i := range dst.ScheduledPatches {
+		scheduledPatch := &dst.ScheduledPatches[i]
+		restoredPatch, ok := restoredPatches[scheduledPatch.Name]
+		if !ok {
+			continue
+		}
+		convertedPatch := *scheduledPatch
+		*scheduledPatch = *restoredPatch
+		scheduledPatch.Schedule = convertedPatch.Schedule
+		scheduledPatch.Timezone = convertedPatch.Timezone
+		scheduledPatch.Patch = restoreHPAPatch(restoredPatch.Patch, convertedPatch.Patch)
+	}
+}
+
+// restoreHPAPatch restores the v1beta1 fields of the HPA patch on top of the patch converted from v1alpha1.
+// The relative replicas are restored unless the replicas are set in v1alpha1.
+func restoreHPAPatch(restored, converted *v1beta1.HPAPatch) *v1beta1.HPAPatch {
+	if converted == nil {
+		return nil
+	}
+	if restored == nil {
+		return converted
+	}
+	patch := *restored
+	if converted.MinReplicas != nil || !isRelativeReplicas(patch.MinReplicas) {
+		patch.MinReplicas = converted.MinReplicas
+	}
+	if converted.MaxReplicas != nil || !isRelativeReplicas(patch.MaxReplicas) {
+		patch.MaxReplicas = converted.MaxReplicas
+	}
+	patch.Metrics = converted.Metrics
+	return &patch
+}
+
+func isRelativeReplicas(replicas *intstr.IntOrString) bool {
+	return replicas != nil && replicas.Type == intstr.String
+}
+
+func convertSpecToV1beta1(in *CronHorizontalPodAutoscalerSpec, out *v1beta1.CronHorizontalPodAutoscalerSpec) {
+	out.Template = &v1beta1.HPATemplate{Spec: in.Template.Spec}
+	if in.Template.Metadata != nil {
+		out.Template.Metadata = &v1beta1.TemplateMetadata{
+			Labels:      in.Template.Metadata.Labels,
+			Annotations: in.Template.Metadata.Annotations,
+		}
+	}
+	if in.ScheduledPatches != nil {
+		out.ScheduledPatches = make([]v1beta1.CronHorizontalPodAutoscalerScheduledPatch, len(in.ScheduledPatches))
+		for i, scheduledPatch := range in.ScheduledPatches {
+			out.ScheduledPatches[i] = v1beta1.CronHorizontalPodAutoscalerScheduledPatch{
+				Name:     scheduledPatch.Name,
+				Schedule: scheduledPatch.Schedule,
+				Timezone: scheduledPatch.Timezone,
+			}
+			if scheduledPatch.Patch != nil {
+				out.ScheduledPatches[i].Patch = &v1beta1.HPAPatch{
+					MinReplicas: convertReplicasToV1beta1(scheduledPatch.Patch.MinReplicas),
+					MaxReplicas: convertReplicasToV1beta1(scheduledPatch.Patch.MaxReplicas),
+					Metrics:     scheduledPatch.Patch.Metrics,
+				}
+			}
+		}
+	}
+}
+
+func convertSpecFromV1beta1(in *v1beta1.CronHorizontalPodAutoscalerSpec, out *CronHorizontalPodAutoscalerSpec) {
+	// The template is empty in v1alpha1 without HPAs generated from it.
+	if in.Template != nil {
+		if in.Template.Metadata != nil {
+			out.Template.Metadata = &TemplateMetadata{
+				Labels:      in.Template.Metadata.Labels,
+				Annotations: in.Template.Metadata.Annotations,
+			}
+		}
+		out.Template.Spec = in.Template.Spec
+	}
+	if in.ScheduledPatches != nil {
+		out.ScheduledPatches = make([]CronHorizontalPodAutoscalerScheduledPatch, len(in.ScheduledPatches))
+		for i, scheduledPatch := range in.ScheduledPatches {
+			out.ScheduledPatches[i] = CronHorizontalPodAutoscalerScheduledPatch{
+				Name:     scheduledPatch.Name,
+				Schedule: scheduledPatch.Schedule,
+				Timezone: scheduledPatch.Timezone,
+			}
+			if scheduledPatch.Patch != nil {
+				out.ScheduledPatches[i].Patch = &HPAPatch{
+					MinReplicas: convertReplicasFromV1beta1(scheduledPatch.Patch.MinReplicas),
+					MaxReplicas: convertReplicasFromV1beta1(scheduledPatch.Patch.MaxReplicas),
+					Metrics:     scheduledPatch.Patch.Metrics,
+				}
+			}
+		}
+	}
+}
+
+func convertReplicasToV1beta1(in *int32) *intstr.IntOrString {
+	if in == nil {
+		return nil
+	}
+	out := intstr.FromInt(int(*in))
+	return &out
+}
+
+// convertReplicasFromV1beta1 drops the relative replicas, which are restored from the conversion data.
+func convertReplicasFromV1beta1(in *intstr.IntOrString) *int32 {
+	if in == nil || in.Type != intstr.Int {
+		return nil
+	}
+	out := in.IntVal
+	return &out
+}
+
+func convertStatusToV1beta1(in *CronHorizontalPodAutoscalerStatus, out *v1beta1.CronHorizontalPodAutoscalerStatus) {
+	out.LastCronTimestamp = in.LastCronTimestamp
+	out.LastScheduledPatchName = in.LastScheduledPatchName
+}
+
+func convertStatusFromV1beta1(in *v1beta1.CronHorizontalPodAutoscalerStatus, out *CronHorizontalPodAutoscalerStatus) {
+	out.LastCronTimestamp = in.LastCronTimestamp
+	out.LastScheduledPatchName = in.LastScheduledPatchName
+}
@@ -0,0 +1,393 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+
+	"github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+const cronHPAManifest = `
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+  labels:
+    app: nginx
+spec:
+  template:
+    metadata:
+      labels:
+        foo: bar
+      annotations:
+        baz: qux
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+      metrics:
+      - type: Resource
+        resource:
+          name: cpu
+          target:
+            type: Utilization
+            averageUtilization: 50
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 1
+      maxReplicas: 5
+      metrics:
+      - type: Resource
+        resource:
+          name: cpu
+          target:
+            type: Utilization
+            averageUtilization: 70
+status:
+  lastCronTimestamp: "2021-10-04T00:00:00Z"
+  lastScheduledPatchName: nighttime
+`
+
+func TestConvertTo(t *testing.T) {
+	src := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), src)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	expected := &v1beta1.CronHorizontalPodAutoscaler{}
+	err = yaml.Unmarshal([]byte(cronHPAManifest), expected)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	dst := &v1beta1.CronHorizontalPodAutoscaler{}
+	err = src.ConvertTo(dst)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, expected, dst) {
+		t.FailNow()
+	}
+
+	restored := &CronHorizontalPodAutoscaler{}
+	err = restored.ConvertFrom(dst)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// No annotation is needed for the spec which v1alpha1 represents.
+	if !assert.NotContains(t, restored.Annotations, conversionDataAnnotation) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src, restored) {
+		t.FailNow()
+	}
+}
+
+func TestConvertFrom(t *testing.T) {
+	src := &v1beta1.CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), src)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	stabilizationWindowSeconds := int32(600)
+	src.Spec.ScheduledPatches[1].Patch.Behavior = &autoscalingv2.HorizontalPodAutoscalerBehavior{
+		ScaleDown: &autoscalingv2.HPAScalingRules{
+			StabilizationWindowSeconds: &stabilizationWindowSeconds,
+		},
+	}
+	src.Spec.ScheduledPatches[1].StrategicMergePatch = &runtime.RawExtension{
+		Raw: []byte(`{"metadata":{"labels":{"foo":"baz"}}}`),
+	}
+	src.Spec.ScheduledPatches[1].JSONPatch = []v1beta1.JSONPatchOperation{
+		{Op: "add", Path: "/spec/metrics/-", Value: &apiextensionsv1.JSON{Raw: []byte(`{"type":"Resource"}`)}},
+	}
+	src.Spec.ScheduledPatches[0].Duration = &metav1.Duration{Duration: 8 * time.Hour}
+	src.Spec.ScheduledPatches[1].EndSchedule = "0 6 * * *"
+	src.Spec.ScheduledPatches[1].StartTime = &metav1.Time{Time: time.Date(2026, 11, 27, 8, 0, 0, 0, time.UTC).Local()}
+	src.Spec.ScheduledPatches[1].EndTime = &metav1.Time{Time: time.Date(2026, 11, 28, 2, 0, 0, 0, time.UTC).Local()}
+	src.Spec.ScheduledPatches[1].Priority = 10
+	replicas := int32(3)
+	src.Spec.ScheduledPatches[1].Replicas = &replicas
+	src.Spec.ScheduledPatches[1].ScaledObjectPatch = &v1beta1.ScaledObjectPatch{
+		MinReplicaCount: &replicas,
+		Triggers:        []v1beta1.ScaledObjectTriggerPatch{{Type: "prometheus", Metadata: map[string]string{"threshold": "100"}}},
+	}
+	src.Spec.ScheduledPatches[1].Patch.MetricsMergeMode = v1beta1.MetricsMergeModeMerge
+	src.Spec.ScheduledPatches[1].Patch.RemoveMetrics = []v1beta1.MetricIdentity{{Type: autoscalingv2.ResourceMetricSourceType, Name: "memory"}}
+	relativeMinReplicas := intstr.FromString("200%")
+	src.Spec.ScheduledPatches[1].Patch.MinReplicas = &relativeMinReplicas
+	startingDeadlineSeconds := int64(600)
+	src.Spec.ScheduledPatches[1].StartingDeadlineSeconds = &startingDeadlineSeconds
+	src.Spec.ScheduledPatches[1].MissedSchedulePolicy = v1beta1.MissedSchedulePolicySkipIfMissed
+	src.Spec.ScheduledPatches[1].LeadTime = &metav1.Duration{Duration: 10 * time.Minute}
+	src.Spec.ScheduledPatches[1].Ramp = &v1beta1.Ramp{Duration: metav1.Duration{Duration: 30 * time.Minute}, Steps: 3}
+	src.Status.CompletedPatchNames = []string{"nighttime"}
+	src.Status.ObservedGeneration = 2
+	nextTime := metav1.NewTime(time.Date(2021, 9, 4, 13, 0, 0, 0, time.UTC).Local())
+	src.Status.ScheduledPatches = []v1beta1.ScheduledPatchStatus{{Name: "daytime", NextTime: &nextTime}, {Name: "nighttime"}}
+	src.Status.NextPatchName = "daytime"
+	src.Status.NextTime = &nextTime
+	src.Status.SkipUntil = &nextTime
+	src.Status.Ramp = &v1beta1.RampStatus{PatchName: "daytime", StartTime: nextTime, FromMinReplicas: 2}
+	src.Status.MissedPatches = []v1beta1.MissedPatchStatus{{Name: "nighttime", StartTime: nextTime}}
+	src.Status.GuardedPatch = &v1beta1.GuardedPatchStatus{Name: "nighttime", Reason: "maxReplicas 5 is below the current replicas 8", RetryTime: nextTime}
+	src.Status.TriggeredPatch = &v1beta1.TriggeredPatchStatus{Name: "daytime", TriggeredTime: nextTime, EndTime: &nextTime}
+	src.Status.Conditions = []metav1.Condition{
+		{Type: v1beta1.ConditionTypeHPASynced, Status: metav1.ConditionTrue, ObservedGeneration: 2, LastTransitionTime: metav1.NewTime(time.Date(2021, 9, 4, 0, 0, 0, 0, time.UTC).Local()), Reason: "Updated", Message: "Updated HPA"},
+	}
+	src.Spec.ExclusionCalendar = &v1beta1.ExclusionCalendar{Dates: []string{"2026-01-01"}}
+	src.Spec.Timezone = "Asia/Tokyo"
+	suspend := true
+	src.Spec.Suspend = &suspend
+	maxUtilizationPercentage := int32(80)
+	src.Spec.ScaleDownGuard = &v1beta1.ScaleDownGuard{Policy: v1beta1.ScaleDownGuardPolicyClamp, MaxUtilizationPercentage: &maxUtilizationPercentage}
+	src.Status.BaselineHPASpec = src.Spec.Template.Spec.DeepCopy()
+	src.Status.Targets = []v1beta1.HPATargetStatus{{Name: "nginx", PatchName: "daytime", BaselineHPASpec: src.Spec.Template.Spec.DeepCopy()}}
+	expected := &CronHorizontalPodAutoscaler{}
+	err = yaml.Unmarshal([]byte(cronHPAManifest), expected)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// The relative replicas are dropped in v1alpha1.
+	expected.Spec.ScheduledPatches[1].Patch.MinReplicas = nil
+
+	dst := &CronHorizontalPodAutoscaler{}
+	err = dst.ConvertFrom(src)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, expected.Spec, dst.Spec) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, expected.Status, dst.Status) {
+		t.FailNow()
+	}
+
+	// Keep only the spec fields which v1alpha1 cannot represent.
+	data := &conversionData{}
+	err = json.Unmarshal([]byte(dst.Annotations[conversionDataAnnotation]), data)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, data.Spec.Template) {
+		t.FailNow()
+	}
+	if !assert.Len(t, data.Spec.ScheduledPatches, 2) {
+		t.FailNow()
+	}
+	if !assert.Empty(t, data.Spec.ScheduledPatches[1].Schedule) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, data.Spec.ScheduledPatches[1].Patch.MaxReplicas) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, data.Spec.ScheduledPatches[1].Patch.Metrics) {
+		t.FailNow()
+	}
+	if !assert.NotContains(t, dst.Annotations[conversionDataAnnotation], "status") {
+		t.FailNow()
+	}
+
+	// Round-trip the spec without loss. The status is kept only as far as v1alpha1 represents it.
+	restored := &v1beta1.CronHorizontalPodAutoscaler{}
+	err = dst.DeepCopy().ConvertTo(restored)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec, restored.Spec) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, v1beta1.CronHorizontalPodAutoscalerStatus{LastCronTimestamp: src.Status.LastCronTimestamp, LastScheduledPatchName: src.Status.LastScheduledPatchName}, restored.Status) {
+		t.FailNow()
+	}
+
+	// Keep the v1beta1 fields of the patches modified in v1alpha1.
+	modified := dst.DeepCopy()
+	maxReplicas := int32(3)
+	modified.Spec.ScheduledPatches[1].Patch.MaxReplicas = &maxReplicas
+	modified.Spec.ScheduledPatches = modified.Spec.ScheduledPatches[1:]
+	restored = &v1beta1.CronHorizontalPodAutoscaler{}
+	err = modified.ConvertTo(restored)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Len(t, restored.Spec.ScheduledPatches, 1) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, intstr.FromInt(3), *restored.Spec.ScheduledPatches[0].Patch.MaxReplicas) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, relativeMinReplicas, *restored.Spec.ScheduledPatches[0].Patch.MinReplicas) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].Patch.RemoveMetrics, restored.Spec.ScheduledPatches[0].Patch.RemoveMetrics) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].Patch.Behavior, restored.Spec.ScheduledPatches[0].Patch.Behavior) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].StrategicMergePatch, restored.Spec.ScheduledPatches[0].StrategicMergePatch) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].JSONPatch, restored.Spec.ScheduledPatches[0].JSONPatch) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].EndSchedule, restored.Spec.ScheduledPatches[0].EndSchedule) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].StartTime, restored.Spec.ScheduledPatches[0].StartTime) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].EndTime, restored.Spec.ScheduledPatches[0].EndTime) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(10), restored.Spec.ScheduledPatches[0].Priority) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].Replicas, restored.Spec.ScheduledPatches[0].Replicas) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].ScaledObjectPatch, restored.Spec.ScheduledPatches[0].ScaledObjectPatch) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].LeadTime, restored.Spec.ScheduledPatches[0].LeadTime) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].StartingDeadlineSeconds, restored.Spec.ScheduledPatches[0].StartingDeadlineSeconds) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].MissedSchedulePolicy, restored.Spec.ScheduledPatches[0].MissedSchedulePolicy) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScheduledPatches[1].Ramp, restored.Spec.ScheduledPatches[0].Ramp) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ExclusionCalendar, restored.Spec.ExclusionCalendar) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.Timezone, restored.Spec.Timezone) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.Suspend, restored.Spec.Suspend) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, src.Spec.ScaleDownGuard, restored.Spec.ScaleDownGuard) {
+		t.FailNow()
+	}
+	if !assert.NotContains(t, restored.Annotations, conversionDataAnnotation) {
+		t.FailNow()
+	}
+
+	// Adopt an HPA without the template.
+	adopted := src.DeepCopy()
+	adopted.Spec.Template = nil
+	adopted.Spec.HPARef = &corev1.LocalObjectReference{Name: "nginx"}
+	dst = &CronHorizontalPodAutoscaler{}
+	err = dst.ConvertFrom(adopted)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, HPATemplate{}, dst.Spec.Template) {
+		t.FailNow()
+	}
+	restored = &v1beta1.CronHorizontalPodAutoscaler{}
+	err = dst.ConvertTo(restored)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, adopted.Spec, restored.Spec) {
+		t.FailNow()
+	}
+
+	// Select HPAs without the template.
+	selected := src.DeepCopy()
+	selected.Spec.Template = nil
+	selected.Spec.HPASelector = &metav1.LabelSelector{MatchLabels: map[string]string{"profile": "web"}}
+	dst = &CronHorizontalPodAutoscaler{}
+	err = dst.ConvertFrom(selected)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	restored = &v1beta1.CronHorizontalPodAutoscaler{}
+	err = dst.ConvertTo(restored)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, selected.Spec, restored.Spec) {
+		t.FailNow()
+	}
+
+	// Scale a workload without the template.
+	scaled := src.DeepCopy()
+	scaled.Spec.Template = nil
+	scaled.Spec.ScaleTarget = &v1beta1.ScaleTarget{
+		ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "nginx"},
+		Replicas:       &replicas,
+	}
+	dst = &CronHorizontalPodAutoscaler{}
+	err = dst.ConvertFrom(scaled)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	restored = &v1beta1.CronHorizontalPodAutoscaler{}
+	err = dst.ConvertTo(restored)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, scaled.Spec, restored.Spec) {
+		t.FailNow()
+	}
+
+	// Patch a KEDA ScaledObject without the template.
+	keda := src.DeepCopy()
+	keda.Spec.Template = nil
+	keda.Spec.ScaledObjectRef = &corev1.LocalObjectReference{Name: "consumer"}
+	keda.Status.BaselineScaledObjectSpec = &apiextensionsv1.JSON{Raw: []byte(`{"minReplicaCount":1}`)}
+	dst = &CronHorizontalPodAutoscaler{}
+	err = dst.ConvertFrom(keda)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	restored = &v1beta1.CronHorizontalPodAutoscaler{}
+	err = dst.ConvertTo(restored)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, keda.Spec, restored.Spec) {
+		t.FailNow()
+	}
+}
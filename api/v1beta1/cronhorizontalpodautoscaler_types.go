@@ -0,0 +1,502 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// TemplateMetadata is a metadata type only for labels and annotations.
+type TemplateMetadata struct {
+	// Map of string keys and values that can be used to organize and categorize
+	// (scope and select) objects. May match selectors of replication controllers
+	// and services.
+	// More info: http://kubernetes.io/docs/user-guide/labels
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+	// Annotations is an unstructured key value map stored with a resource that may be
+	// set by external tools to store and retrieve arbitrary metadata. They are not
+	// queryable and should be preserved when modifying objects.
+	// More info: http://kubernetes.io/docs/user-guide/annotations
+	// +optional
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// HPATemplate is the template of HPA to create.
+// The spec is shared by autoscaling/v2 and autoscaling/v2beta2, and the HPA is created
+// in whichever version the cluster serves.
+type HPATemplate struct {
+	Metadata *TemplateMetadata                         `json:"metadata,omitempty"`
+	Spec     autoscalingv2.HorizontalPodAutoscalerSpec `json:"spec"`
+}
+
+// HPAPatch is a patch applied to the template.
+type HPAPatch struct {
+	// minReplicas is the lower limit for the number of replicas to which the autoscaler
+	// can scale down.  It defaults to 1 pod.  minReplicas is allowed to be 0 if the
+	// alpha feature gate HPAScaleToZero is enabled and at least one Object or External
+	// metric is configured.  Scaling is active as long as at least one metric value is
+	// available.
+	// It can be relative to minReplicas of the template, or the baseline of the adopted HPA, like a percentage
+	// `200%`, which is rounded up, or an offset `+5` or `-2`. A relative value is at least 1 and at most maxReplicas.
+	// +optional
+	MinReplicas *intstr.IntOrString `json:"minReplicas,omitempty"`
+	// maxReplicas is the upper limit for the number of replicas to which the autoscaler can scale up.
+	// It cannot be less that minReplicas.
+	// It can be relative to maxReplicas of the template, or the baseline of the adopted HPA, like minReplicas.
+	// A relative value is at least 1 and at least minReplicas.
+	// +optional
+	MaxReplicas *intstr.IntOrString `json:"maxReplicas,omitempty"`
+	// metrics contains the specifications for which to use to calculate the
+	// desired replica count (the maximum replica count across all metrics will
+	// be used).  The desired replica count is calculated multiplying the
+	// ratio between the target value and the current value by the current
+	// number of pods.  Ergo, metrics used must decrease as the pod count is
+	// increased, and vice-versa.  See the individual metric source types for
+	// more information about how each type of metric must respond.
+	// +optional
+	Metrics []autoscalingv2.MetricSpec `json:"metrics,omitempty"`
+	// MetricsMergeMode is how to apply Metrics to the metrics of the template, `Replace` or `Merge`.
+	// Replace replaces the whole list. Merge overrides the targets of the metrics of the same identity,
+	// i.e. the type and the name, the container, the selector or the described object, and appends the others.
+	// Defaults to `Replace`.
+	// +kubebuilder:validation:Enum=Replace;Merge
+	// +optional
+	MetricsMergeMode string `json:"metricsMergeMode,omitempty"`
+	// RemoveMetrics are the metrics of the template to remove in the Merge mode.
+	// +optional
+	RemoveMetrics []MetricIdentity `json:"removeMetrics,omitempty"`
+	// behavior configures the scaling behavior of the target in both Up and Down directions.
+	// The scaleUp and scaleDown rules are merged into the template's ones. The stabilization
+	// window and the select policy override the template's ones if set, and the policies
+	// replace the template's policies of the same type.
+	// +optional
+	Behavior *autoscalingv2.HorizontalPodAutoscalerBehavior `json:"behavior,omitempty"`
+}
+
+// MetricIdentity identifies a metric of the HPA.
+type MetricIdentity struct {
+	// Type is the type of the metric, e.g. `Resource`.
+	Type autoscalingv2.MetricSourceType `json:"type"`
+	// Name is the name of the resource for Resource and ContainerResource metrics, or the name of the metric.
+	Name string `json:"name"`
+	// Container is the name of the container of the ContainerResource metric.
+	// +optional
+	Container string `json:"container,omitempty"`
+	// Selector is the selector of the metric for Pods, Object and External metrics.
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+	// DescribedObject is the object described by the Object metric.
+	// +optional
+	DescribedObject *autoscalingv2.CrossVersionObjectReference `json:"describedObject,omitempty"`
+}
+
+// JSONPatchOperation is an operation of RFC 6902 JSON patch.
+type JSONPatchOperation struct {
+	// Op is the operation to perform.
+	// +kubebuilder:validation:Enum=add;remove;replace;move;copy;test
+	Op string `json:"op"`
+	// Path is a JSON pointer to the target location in the HPA.
+	Path string `json:"path"`
+	// From is a JSON pointer to the source location in the HPA for move and copy operations.
+	// +optional
+	From string `json:"from,omitempty"`
+	// Value is the value to add, replace or test.
+	// +optional
+	Value *apiextensionsv1.JSON `json:"value,omitempty"`
+}
+
+// ScaledObjectPatch is a patch applied to a KEDA ScaledObject.
+type ScaledObjectPatch struct {
+	// MinReplicaCount is the minimum number of replicas of the ScaledObject.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReplicaCount *int32 `json:"minReplicaCount,omitempty"`
+	// MaxReplicaCount is the maximum number of replicas of the ScaledObject.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MaxReplicaCount *int32 `json:"maxReplicaCount,omitempty"`
+	// Triggers are the patches of the metadata of the triggers.
+	// +optional
+	Triggers []ScaledObjectTriggerPatch `json:"triggers,omitempty"`
+}
+
+// ScaledObjectTriggerPatch is a patch of the metadata of the triggers of a KEDA ScaledObject.
+type ScaledObjectTriggerPatch struct {
+	// Name is the name of the triggers to patch. The triggers are matched by Type if it's empty.
+	// +optional
+	Name string `json:"name,omitempty"`
+	// Type is the type of the triggers to patch, e.g. `prometheus`.
+	// +optional
+	Type string `json:"type,omitempty"`
+	// Metadata is merged into the metadata of the triggers.
+	Metadata map[string]string `json:"metadata"`
+}
+
+// Ramp changes minReplicas gradually to the value of the patch.
+type Ramp struct {
+	// Duration is the time to reach minReplicas of the patch from the start of the patch, like `30m`.
+	Duration metav1.Duration `json:"duration"`
+	// Steps is the number of the changes of minReplicas in Duration.
+	// +kubebuilder:validation:Minimum=1
+	Steps int32 `json:"steps"`
+}
+
+// CronHorizontalPodAutoscalerScheduledPatch is a patch w/ schedule to apply.
+type CronHorizontalPodAutoscalerScheduledPatch struct {
+	// Name is the name of this schedule.
+	// +kubebuilder:validation:MaxLength=16
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:Pattern=[a-zA-Z0-9\-]+
+	Name string `json:"name"`
+	// Schedule is a schedule to apply the HPA in the cron format like `0 */2 * * *`.
+	// See https://pkg.go.dev/github.com/robfig/cron
+	// It is required unless StartTime is set.
+	// +optional
+	Schedule string `json:"schedule,omitempty"`
+	// Timezone is a timezone of the schedule.
+	// Defaults to the timezone of the spec, or the default timezone of the controller.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+	// StartTime is the time to apply the patch only once, instead of Schedule.
+	// EndTime is required with StartTime.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// EndTime is the time when the patch started at StartTime expires.
+	// +optional
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+	// StartingDeadlineSeconds is the deadline in seconds to apply the patch after its start which the controller
+	// missed, e.g. while it was down. The patch missed beyond the deadline is handled by MissedSchedulePolicy.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	StartingDeadlineSeconds *int64 `json:"startingDeadlineSeconds,omitempty"`
+	// MissedSchedulePolicy is how to handle the patch missed beyond StartingDeadlineSeconds, `ApplyLatest`,
+	// `SkipIfMissed` or `ApplyTemplate`. ApplyLatest applies the patch anyway, SkipIfMissed keeps the patch
+	// applied before, and ApplyTemplate applies the template, or the baseline. Defaults to `ApplyLatest`.
+	// +kubebuilder:validation:Enum=ApplyLatest;SkipIfMissed;ApplyTemplate
+	// +optional
+	MissedSchedulePolicy string `json:"missedSchedulePolicy,omitempty"`
+	// LeadTime is the time to apply the patch ahead of the schedule, or StartTime, like `10m`,
+	// e.g. to warm up the pods in advance. The status and the events report the scheduled time.
+	// +optional
+	LeadTime *metav1.Duration `json:"leadTime,omitempty"`
+	// Priority is the priority of the patch when multiple patches are active. The higher one wins,
+	// and the most recently started one wins among the same priority. Defaults to 0.
+	// A patch without a window stays active until a patch with the same or a higher priority fires.
+	// +optional
+	Priority int32 `json:"priority,omitempty"`
+	// Duration is the length of the window in which the patch is active, like `8h`.
+	// The patch stays active until another patch is scheduled if neither Duration nor EndSchedule is set.
+	// +optional
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// EndSchedule is a schedule to close the window in which the patch is active in the cron format.
+	// The window is closed at the first schedule after its start in the same timezone.
+	// +optional
+	EndSchedule string `json:"endSchedule,omitempty"`
+	// Ramp changes minReplicas of the HPA gradually from the value before the patch.
+	// It's used only with Template or HPARef.
+	// +optional
+	Ramp *Ramp `json:"ramp,omitempty"`
+	// Replicas is the number of replicas of the scale target at the schedule.
+	// It's used only with ScaleTarget instead of Patch.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+	// ScaledObjectPatch is a patch to apply to the KEDA ScaledObject at the schedule.
+	// It's used only with ScaledObjectRef instead of Patch.
+	// +optional
+	ScaledObjectPatch *ScaledObjectPatch `json:"scaledObjectPatch,omitempty"`
+	// Patch is a patch to apply to the template at the schedule.
+	Patch *HPAPatch `json:"patch,omitempty"`
+	// StrategicMergePatch is a strategic merge patch applied to the spec and the metadata of the
+	// generated HPA after Patch, e.g. `{"spec": {"minReplicas": 3}}`.
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +optional
+	StrategicMergePatch *runtime.RawExtension `json:"strategicMergePatch,omitempty"`
+	// JSONPatch is a list of RFC 6902 JSON patch operations applied to the spec and the metadata
+	// of the generated HPA after StrategicMergePatch.
+	// +optional
+	JSONPatch []JSONPatchOperation `json:"jsonPatch,omitempty"`
+}
+
+// ScaleTarget is a workload whose replicas are scaled directly through the scale subresource without an HPA.
+type ScaleTarget struct {
+	// ScaleTargetRef refers to the workload with the scale subresource, e.g. a Deployment.
+	ScaleTargetRef autoscalingv2.CrossVersionObjectReference `json:"scaleTargetRef"`
+	// Replicas is the number of replicas when no patch is active. The replicas are kept as is if empty.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	Replicas *int32 `json:"replicas,omitempty"`
+}
+
+// ExclusionCalendar is a set of dates on which the scheduled patches don't fire.
+type ExclusionCalendar struct {
+	// Dates are the excluded dates like `2026-01-01`.
+	// +optional
+	Dates []string `json:"dates,omitempty"`
+	// ConfigMapRef refers to a key of a ConfigMap in the same namespace which contains an iCalendar file.
+	// The dates of its events are excluded.
+	// +optional
+	ConfigMapRef *corev1.ConfigMapKeySelector `json:"configMapRef,omitempty"`
+}
+
+// ScaleDownGuard guards the HPA from scaling in abruptly by the scheduled patches.
+type ScaleDownGuard struct {
+	// Policy is how to guard a patch which is not safe to apply, `Defer` or `Clamp`. Defer keeps the HPA
+	// unchanged, and Clamp applies the patch without lowering the replicas. Defaults to `Defer`.
+	// +kubebuilder:validation:Enum=Defer;Clamp
+	// +optional
+	Policy string `json:"policy,omitempty"`
+	// MaxUtilizationPercentage is the threshold of the current average utilization of the resource metrics of the HPA.
+	// A patch which lowers minReplicas or maxReplicas is not safe while any of them is above it.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxUtilizationPercentage *int32 `json:"maxUtilizationPercentage,omitempty"`
+	// RetryInterval is the interval to retry the guarded patch until it becomes safe, like `1m`. Defaults to `1m`.
+	// +optional
+	RetryInterval *metav1.Duration `json:"retryInterval,omitempty"`
+}
+
+// CronHorizontalPodAutoscalerSpec defines the desired state of CronHorizontalPodAutoscaler
+type CronHorizontalPodAutoscalerSpec struct {
+	// Template is the template of HPA. One of Template, HPARef, HPASelector, ScaleTarget and ScaledObjectRef is required.
+	// +optional
+	Template *HPATemplate `json:"template,omitempty"`
+	// HPARef refers to an existing HPA in the same namespace to adopt instead of generating one from Template.
+	// The spec of the HPA is captured as the baseline, and the scheduled patches are applied on top of it.
+	// The baseline is restored when no patch is active.
+	// +optional
+	HPARef *corev1.LocalObjectReference `json:"hpaRef,omitempty"`
+	// HPASelector selects existing HPAs in the same namespace to adopt like HPARef.
+	// The scheduled patches are applied to each of them on top of its own baseline.
+	// HPAs controlled by CronHPAs are not selected.
+	// +optional
+	HPASelector *metav1.LabelSelector `json:"hpaSelector,omitempty"`
+	// ScaleTarget is a workload to scale directly with the replicas of the scheduled patches instead of an HPA.
+	// +optional
+	ScaleTarget *ScaleTarget `json:"scaleTarget,omitempty"`
+	// ScaledObjectRef refers to a KEDA ScaledObject in the same namespace to patch instead of an HPA.
+	// The spec of the ScaledObject is captured as the baseline like HPARef.
+	// +optional
+	ScaledObjectRef *corev1.LocalObjectReference `json:"scaledObjectRef,omitempty"`
+	// schedules contain the specifications of HPA with a schedule.
+	ScheduledPatches []CronHorizontalPodAutoscalerScheduledPatch `json:"scheduledPatches"`
+	// Timezone is the default timezone of the scheduled patches.
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+	// ExclusionCalendar is a set of dates on which the scheduled patches don't fire.
+	// The dates are evaluated in the timezone of each patch. Patches with StartTime are not affected.
+	// +optional
+	ExclusionCalendar *ExclusionCalendar `json:"exclusionCalendar,omitempty"`
+	// Suspend tells the controller to suspend applying the scheduled patches. The next schedules are still
+	// computed in the status, and the patch active at the time is applied on resume. Defaults to false.
+	// +optional
+	Suspend *bool `json:"suspend,omitempty"`
+	// ScaleDownGuard defers or clamps the patches which drop maxReplicas below the current replicas of the HPA,
+	// or lower the replicas while the utilization is high. It's available with Template or HPARef.
+	// +optional
+	ScaleDownGuard *ScaleDownGuard `json:"scaleDownGuard,omitempty"`
+}
+
+// ScheduledPatchStatus is the observed state of a scheduled patch.
+type ScheduledPatchStatus struct {
+	// Name is the name of the scheduled patch.
+	Name string `json:"name"`
+	// NextTime is the next time when the patch fires.
+	// +optional
+	NextTime *metav1.Time `json:"nextTime,omitempty"`
+}
+
+// HPATargetStatus is the observed state of an HPA selected by HPASelector.
+type HPATargetStatus struct {
+	// Name is the name of the HPA.
+	Name string `json:"name"`
+	// PatchName is the name of the patch applied to the HPA. The baseline is applied if empty.
+	// +optional
+	PatchName string `json:"patchName,omitempty"`
+	// BaselineHPASpec is the spec of the HPA without patches.
+	// +optional
+	BaselineHPASpec *autoscalingv2.HorizontalPodAutoscalerSpec `json:"baselineHPASpec,omitempty"`
+	// Skipped is true if updating the HPA is skipped by the annotation.
+	// +optional
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+// TriggeredPatchStatus is the state of the scheduled patch triggered manually by the trigger annotation.
+type TriggeredPatchStatus struct {
+	// Name is the name of the triggered patch.
+	Name string `json:"name"`
+	// TriggeredTime is the time when the patch was triggered.
+	TriggeredTime metav1.Time `json:"triggeredTime"`
+	// EndTime is the time when the triggered patch ends.
+	// The triggered patch is active until another patch starts if it's empty.
+	// +optional
+	EndTime *metav1.Time `json:"endTime,omitempty"`
+}
+
+// RampStatus is the state of the ramp of minReplicas.
+type RampStatus struct {
+	// PatchName is the name of the patch with the ramp.
+	PatchName string `json:"patchName"`
+	// StartTime is the time when the ramp started.
+	StartTime metav1.Time `json:"startTime"`
+	// FromMinReplicas is minReplicas of the HPA when the ramp started.
+	FromMinReplicas int32 `json:"fromMinReplicas"`
+}
+
+// GuardedPatchStatus is the state of the scheduled patch guarded by ScaleDownGuard.
+type GuardedPatchStatus struct {
+	// Name is the name of the guarded patch.
+	Name string `json:"name"`
+	// Reason is why the patch is not safe to apply.
+	Reason string `json:"reason"`
+	// RetryTime is the time to retry the patch.
+	RetryTime metav1.Time `json:"retryTime"`
+}
+
+// MissedPatchStatus is a scheduled patch whose start was missed beyond its starting deadline.
+type MissedPatchStatus struct {
+	// Name is the name of the missed patch.
+	Name string `json:"name"`
+	// StartTime is the missed start of the patch.
+	StartTime metav1.Time `json:"startTime"`
+}
+
+// CronHorizontalPodAutoscalerStatus defines the observed state of CronHorizontalPodAutoscaler.
+type CronHorizontalPodAutoscalerStatus struct {
+	// LastCronTimestamp is the time of last cron job.
+	LastCronTimestamp *metav1.Time `json:"lastCronTimestamp,omitempty"`
+	// LastScheduledPatchName is the last patch name applied to the HPA, i.e. the active patch.
+	LastScheduledPatchName string `json:"lastScheduledPatchName,omitempty"`
+	// ScheduledPatches are the observed states of the scheduled patches.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	ScheduledPatches []ScheduledPatchStatus `json:"scheduledPatches,omitempty"`
+	// NextPatchName is the name of the patch which fires next.
+	// +optional
+	NextPatchName string `json:"nextPatchName,omitempty"`
+	// NextTime is the next time when a patch fires.
+	// +optional
+	NextTime *metav1.Time `json:"nextTime,omitempty"`
+	// BaselineHPASpec is the spec of the adopted HPA without patches.
+	// It's captured when the HPA is adopted, and when the HPA is changed while no patch is applied.
+	// +optional
+	BaselineHPASpec *autoscalingv2.HorizontalPodAutoscalerSpec `json:"baselineHPASpec,omitempty"`
+	// BaselineScaledObjectSpec is the spec of the ScaledObject referred by ScaledObjectRef without patches.
+	// +optional
+	BaselineScaledObjectSpec *apiextensionsv1.JSON `json:"baselineScaledObjectSpec,omitempty"`
+	// Targets are the observed states of the HPAs selected by HPASelector.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	Targets []HPATargetStatus `json:"targets,omitempty"`
+	// TriggeredPatch is the scheduled patch triggered manually.
+	// +optional
+	TriggeredPatch *TriggeredPatchStatus `json:"triggeredPatch,omitempty"`
+	// Ramp is the state of the ramp of the active patch.
+	// +optional
+	Ramp *RampStatus `json:"ramp,omitempty"`
+	// MissedPatches are the patches whose latest starts were missed beyond their starting deadlines.
+	// +listType=map
+	// +listMapKey=name
+	// +optional
+	MissedPatches []MissedPatchStatus `json:"missedPatches,omitempty"`
+	// GuardedPatch is the active patch deferred or clamped by ScaleDownGuard.
+	// +optional
+	GuardedPatch *GuardedPatchStatus `json:"guardedPatch,omitempty"`
+	// SkipUntil is the earliest time when the skip-until annotation on the target expires.
+	// The controller reconciles the CronHorizontalPodAutoscaler again at the time.
+	// +optional
+	SkipUntil *metav1.Time `json:"skipUntil,omitempty"`
+	// CompletedPatchNames are the names of the patches with StartTime and EndTime which have expired.
+	// +optional
+	CompletedPatchNames []string `json:"completedPatchNames,omitempty"`
+	// ObservedGeneration is the generation of the CronHorizontalPodAutoscaler observed by the controller.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the latest observations of the CronHorizontalPodAutoscaler's state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// ConditionTypeSchedulesValid indicates whether the schedules and the exclusion calendar are valid.
+	ConditionTypeSchedulesValid = "SchedulesValid"
+	// ConditionTypeHPASynced indicates whether the HPA is synced with the current patch.
+	ConditionTypeHPASynced = "HPASynced"
+	// ConditionTypeSuspended indicates whether updating the HPA is suspended.
+	ConditionTypeSuspended = "Suspended"
+)
+
+const (
+	// MetricsMergeModeReplace replaces the metrics of the template with the metrics of the patch.
+	MetricsMergeModeReplace = "Replace"
+	// MetricsMergeModeMerge merges the metrics of the patch into the metrics of the template by identity.
+	MetricsMergeModeMerge = "Merge"
+)
+
+const (
+	// MissedSchedulePolicyApplyLatest applies the latest patch even if it's missed beyond the starting deadline.
+	MissedSchedulePolicyApplyLatest = "ApplyLatest"
+	// MissedSchedulePolicySkipIfMissed keeps the patch applied before if the patch is missed beyond the starting deadline.
+	MissedSchedulePolicySkipIfMissed = "SkipIfMissed"
+	// MissedSchedulePolicyApplyTemplate applies the template if the patch is missed beyond the starting deadline.
+	MissedSchedulePolicyApplyTemplate = "ApplyTemplate"
+)
+
+const (
+	// ScaleDownGuardPolicyDefer keeps the HPA unchanged until the patch becomes safe to apply.
+	ScaleDownGuardPolicyDefer = "Defer"
+	// ScaleDownGuardPolicyClamp applies the patch without lowering the replicas until the patch becomes safe to apply.
+	ScaleDownGuardPolicyClamp = "Clamp"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=cronhpa
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+//+kubebuilder:printcolumn:name="Active",type=string,JSONPath=`.status.lastScheduledPatchName`
+//+kubebuilder:printcolumn:name="Next Patch",type=string,JSONPath=`.status.nextPatchName`
+//+kubebuilder:printcolumn:name="Next Time",type=string,JSONPath=`.status.nextTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CronHorizontalPodAutoscaler is the Schema for the cronhorizontalpodautoscalers API.
+type CronHorizontalPodAutoscaler struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CronHorizontalPodAutoscalerSpec   `json:"spec,omitempty"`
+	Status CronHorizontalPodAutoscalerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CronHorizontalPodAutoscalerList contains a list of CronHorizontalPodAutoscaler
+type CronHorizontalPodAutoscalerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CronHorizontalPodAutoscaler `json:"items"`
+}
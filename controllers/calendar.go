@@ -0,0 +1,154 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const dateFormat = "2006-01-02"
+
+// ExclusionCalendar is a set of excluded dates in the format of `2006-01-02`.
+type ExclusionCalendar map[string]bool
+
+// Excludes returns true if the date of the given time is excluded.
+func (c ExclusionCalendar) Excludes(t time.Time) bool {
+	return c[t.Format(dateFormat)]
+}
+
+// GetExclusionCalendar resolves the excluded dates of the inline list and the referenced ConfigMap.
+func (cronhpa *CronHorizontalPodAutoscaler) GetExclusionCalendar(ctx context.Context, reader client.Reader) (ExclusionCalendar, error) {
+	calendar := ExclusionCalendar{}
+	exclusionCalendar := cronhpa.Spec.ExclusionCalendar
+	if exclusionCalendar == nil {
+		return calendar, nil
+	}
+	for _, date := range exclusionCalendar.Dates {
+		if _, err := time.Parse(dateFormat, date); err != nil {
+			return nil, fmt.Errorf("Invalid excluded date %s: %w", date, err)
+		}
+		calendar[date] = true
+	}
+	ref := exclusionCalendar.ConfigMapRef
+	if ref == nil {
+		return calendar, nil
+	}
+	optional := ref.Optional != nil && *ref.Optional
+	configMap := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: cronhpa.Namespace, Name: ref.Name}, configMap); err != nil {
+		if errors.IsNotFound(err) && optional {
+			return calendar, nil
+		}
+		return nil, err
+	}
+	data, ok := configMap.Data[ref.Key]
+	if !ok {
+		if optional {
+			return calendar, nil
+		}
+		return nil, fmt.Errorf("Key %s is not found in ConfigMap %s", ref.Key, ref.Name)
+	}
+	dates, err := ParseICalendar(data)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse ConfigMap %s: %w", ref.Name, err)
+	}
+	for _, date := range dates {
+		calendar[date] = true
+	}
+	return calendar, nil
+}
+
+// IsExcluded returns true if the patch doesn't fire at the given time because of the calendar.
+// The date is of the scheduled time if the patch fires ahead by the lead time.
+func (cronhpa *CronHorizontalPodAutoscaler) IsExcluded(patchName string, currentTime time.Time, calendar ExclusionCalendar) (bool, error) {
+	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		if scheduledPatch.Name != patchName || isDatedPatch(&scheduledPatch) {
+			continue
+		}
+		location, err := loadLocation(scheduledPatch.Timezone)
+		if err != nil {
+			return false, err
+		}
+		return calendar.Excludes(currentTime.Add(getLeadTime(&scheduledPatch)).In(location)), nil
+	}
+	return false, nil
+}
+
+// ParseICalendar returns the dates of the events in an iCalendar file.
+// All-day events cover the dates until DTEND, and the other events cover the date of DTSTART.
+// Recurrence rules are not supported.
+func ParseICalendar(data string) ([]string, error) {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	dates := make([]string, 0)
+	inEvent := false
+	var startTime, endTime time.Time
+	allDay := false
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "BEGIN:VEVENT":
+			inEvent = true
+			startTime, endTime, allDay = time.Time{}, time.Time{}, false
+		case line == "END:VEVENT":
+			if startTime.IsZero() {
+				return nil, fmt.Errorf("An event without DTSTART")
+			}
+			dates = append(dates, startTime.Format(dateFormat))
+			if allDay {
+				for t := startTime.AddDate(0, 0, 1); t.Before(endTime); t = t.AddDate(0, 0, 1) {
+					dates = append(dates, t.Format(dateFormat))
+				}
+			}
+			inEvent = false
+		case inEvent:
+			i := strings.Index(line, ":")
+			if i < 0 {
+				continue
+			}
+			name := strings.SplitN(line[:i], ";", 2)[0]
+			value := line[i+1:]
+			if name != "DTSTART" && name != "DTEND" {
+				continue
+			}
+			if len(value) < 8 {
+				return nil, fmt.Errorf("Invalid %s %s", name, value)
+			}
+			t, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return nil, fmt.Errorf("Invalid %s %s: %w", name, value, err)
+			}
+			if name == "DTSTART" {
+				startTime = t
+				allDay = len(value) == 8
+			} else {
+				endTime = t
+			}
+		}
+	}
+	return dates, nil
+}
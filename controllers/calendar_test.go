@@ -0,0 +1,243 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+const holidaysICalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Culture Day\r\n" +
+	"DTSTART;VALUE=DATE:20211103\r\n" +
+	"DTEND;VALUE=DATE:20211104\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:New Year\r\n" +
+	" Holidays\r\n" +
+	"DTSTART;VALUE=DATE:20211231\r\n" +
+	"DTEND;VALUE=DATE:20220104\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Maintenance\r\n" +
+	"DTSTART;TZID=Asia/Tokyo:20211120T100000\r\n" +
+	"DTEND;TZID=Asia/Tokyo:20211120T120000\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICalendar(t *testing.T) {
+	dates, err := ParseICalendar(holidaysICalendar)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, []string{"2021-11-03", "2021-12-31", "2022-01-01", "2022-01-02", "2022-01-03", "2021-11-20"}, dates) {
+		t.FailNow()
+	}
+
+	_, err = ParseICalendar("BEGIN:VEVENT\nSUMMARY:Broken\nEND:VEVENT\n")
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+	_, err = ParseICalendar("BEGIN:VEVENT\nDTSTART:2021\nEND:VEVENT\n")
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+}
+
+func TestGetExclusionCalendar(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: business
+    schedule: "0 9 * * mon-fri"
+    endSchedule: "0 18 * * mon-fri"
+    timezone: "Asia/Tokyo"
+  exclusionCalendar:
+    dates:
+    - "2021-11-23"
+    configMapRef:
+      name: holidays
+      key: holidays.ics
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "holidays", Namespace: "default"},
+		Data:       map[string]string{"holidays.ics": holidaysICalendar},
+	}
+	calendar, err := cronhpa.GetExclusionCalendar(ctx, fake.NewClientBuilder().WithObjects(configMap).Build())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, date := range []string{"2021-11-03", "2021-11-23", "2022-01-03"} {
+		if !assert.True(t, calendar[date], date) {
+			t.FailNow()
+		}
+	}
+	if !assert.Len(t, calendar, 7) {
+		t.FailNow()
+	}
+
+	// Missing ConfigMap.
+	_, err = cronhpa.GetExclusionCalendar(ctx, fake.NewClientBuilder().Build())
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+	optional := true
+	cronhpa.Spec.ExclusionCalendar.ConfigMapRef.Optional = &optional
+	calendar, err = cronhpa.GetExclusionCalendar(ctx, fake.NewClientBuilder().Build())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, ExclusionCalendar{"2021-11-23": true}, calendar) {
+		t.FailNow()
+	}
+
+	// Excluded in the timezone of the patch.
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-11-23T09:00:00+09:00"))
+	excluded, err := cronhpa.IsExcluded("business", currentTime, calendar)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, excluded) {
+		t.FailNow()
+	}
+	_ = currentTime.UnmarshalText([]byte("2021-11-22T23:00:00Z")) // 2021-11-23 08:00 in Asia/Tokyo
+	excluded, err = cronhpa.IsExcluded("business", currentTime, calendar)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, excluded) {
+		t.FailNow()
+	}
+	_ = currentTime.UnmarshalText([]byte("2021-11-24T09:00:00+09:00"))
+	excluded, err = cronhpa.IsExcluded("business", currentTime, calendar)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.False(t, excluded) {
+		t.FailNow()
+	}
+}
+
+func TestGetCurrentPatchNameWithExclusionCalendar(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+  - name: business
+    schedule: "0 9 * * mon-fri"
+    endSchedule: "0 18 * * mon-fri"
+    timezone: "Asia/Tokyo"
+status:
+  lastCronTimestamp: "2021-11-22T22:00:00+09:00"
+  lastScheduledPatchName: nighttime
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	calendar := ExclusionCalendar{"2021-11-23": true}
+
+	for _, c := range []struct {
+		currentTime string
+		patchName   string
+	}{
+		{"2021-11-23T10:00:00+09:00", "nighttime"}, // Tue, excluded
+		{"2021-11-24T08:30:00+09:00", "daytime"},
+		{"2021-11-24T10:00:00+09:00", "business"},
+	} {
+		currentTime := time.Time{}
+		_ = currentTime.UnmarshalText([]byte(c.currentTime))
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, calendar)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestIndexConfigMapRef(t *testing.T) {
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	if !assert.Empty(t, indexConfigMapRef(cronhpa.ToCompatible())) {
+		t.FailNow()
+	}
+	cronhpa.Spec.ExclusionCalendar = &cronhpav1beta1.ExclusionCalendar{Dates: []string{"2021-11-03"}}
+	if !assert.Empty(t, indexConfigMapRef(cronhpa.ToCompatible())) {
+		t.FailNow()
+	}
+	cronhpa.Spec.ExclusionCalendar.ConfigMapRef = &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "holidays"}, Key: "holidays.ics"}
+	if !assert.Equal(t, []string{"holidays"}, indexConfigMapRef(cronhpa.ToCompatible())) {
+		t.FailNow()
+	}
+}
@@ -18,18 +18,28 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
-	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/scale"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
-	cronhpav1alpha1 "github.com/dtaniwaki/cron-hpa/api/v1alpha1"
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
 )
 
 // CronHorizontalPodAutoscalerReconciler reconciles a CronHorizontalPodAutoscaler object
@@ -37,15 +47,28 @@ type CronHorizontalPodAutoscalerReconciler struct {
 	client.Client
 	Recorder record.EventRecorder
 	Cron     *Cron
+	// DefaultTimezone is the timezone of the scheduled patches without timezones in the cluster.
+	DefaultTimezone string
+	// HPAGroupVersion is the API version of HPAs to manage. autoscaling/v2 is used if empty.
+	HPAGroupVersion schema.GroupVersion
+	// ScaleClient scales the scale targets through the scale subresource.
+	ScaleClient scale.ScalesGetter
 }
 
 const finalizerName = "cron-hpa.dtaniwaki.github.com/finalizer"
 
+// configMapRefIndexKey is the field index of the CronHPAs by the ConfigMaps of their exclusion calendars.
+const configMapRefIndexKey = "spec.exclusionCalendar.configMapRef.name"
+
 //+kubebuilder:rbac:groups=cron-hpa.dtaniwaki.github.com,resources=cronhorizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=cron-hpa.dtaniwaki.github.com,resources=cronhorizontalpodautoscalers/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=cron-hpa.dtaniwaki.github.com,resources=cronhorizontalpodautoscalers/finalizers,verbs=update
 //+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=*,resources=*/scale,verbs=get;update;patch
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;replicasets,verbs=get;patch
+//+kubebuilder:rbac:groups=keda.sh,resources=scaledobjects,verbs=get;list;watch;update;patch
 
 func (r *CronHorizontalPodAutoscalerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
@@ -70,6 +93,9 @@ func (r *CronHorizontalPodAutoscalerReconciler) Reconcile(ctx context.Context, r
 			if err := cronhpa.ClearSchedules(ctx, r); err != nil {
 				logger.Error(err, "Failed to clear schedules")
 			}
+			if err := cronhpa.RestoreBaselineHPA(ctx, r); err != nil {
+				logger.Error(err, "Failed to restore the baseline")
+			}
 
 			controllerutil.RemoveFinalizer(cronhpa.ToCompatible(), finalizerName)
 			if err := r.Update(ctx, cronhpa.ToCompatible()); err != nil {
@@ -88,37 +114,167 @@ func (r *CronHorizontalPodAutoscalerReconciler) Reconcile(ctx context.Context, r
 		}
 	}
 
+	// Start the patch triggered manually before the timezones are defaulted in the spec.
+	if err := cronhpa.UpdateTriggeredPatch(ctx, now, r); err != nil {
+		return ctrl.Result{}, err
+	}
+
 	// Fetch the corresponded HPA instance.
 	logger.Info("Fetch HPA")
-	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{}
-	if err := r.Get(ctx, req.NamespacedName, hpa); err != nil {
+	hpa, err := NewVersionedHPA(r.GetHPAGroupVersion())
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.Get(ctx, cronhpa.ToHPANamespacedName(), hpa); err != nil {
 		if !errors.IsNotFound(err) {
 			return ctrl.Result{}, err
 		}
 	}
 
+	// Apply the default timezones here too in case the mutating webhook is disabled.
+	cronhpa.SetDefaultTimezones(r.DefaultTimezone)
+	if err := cronhpa.ValidateSchedules(); err != nil {
+		return ctrl.Result{}, cronhpa.UpdateConditionWithError(ctx, r, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidSchedule, err)
+	}
+	calendar, err := cronhpa.GetExclusionCalendar(ctx, r)
+	if err != nil {
+		return ctrl.Result{}, cronhpa.UpdateConditionWithError(ctx, r, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidExclusionCalendar, err)
+	}
+
 	logger.Info("Create or update HPA")
-	patchName, err := cronhpa.GetCurrentPatchName(ctx, now)
+	if !cronhpa.IsSuspended() {
+		if err := cronhpa.UpdateMissedPatches(ctx, now, calendar, r); err != nil {
+			return ctrl.Result{}, cronhpa.UpdateConditionWithError(ctx, r, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidSchedule, err)
+		}
+	}
+	patchName, err := cronhpa.GetCurrentPatchName(ctx, now, calendar)
 	if err != nil {
-		return ctrl.Result{}, err
+		return ctrl.Result{}, cronhpa.UpdateConditionWithError(ctx, r, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidSchedule, err)
 	}
-	if err := cronhpa.CreateOrPatchHPA(ctx, patchName, now, r); err != nil {
-		return ctrl.Result{}, err
+	if err := cronhpa.SetNextSchedules(now, calendar); err != nil {
+		return ctrl.Result{}, cronhpa.UpdateConditionWithError(ctx, r, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidSchedule, err)
+	}
+	// The condition and the next schedules are saved with the status updated by CreateOrPatchHPA.
+	cronhpa.SetCondition(cronhpav1beta1.ConditionTypeSchedulesValid, metav1.ConditionTrue, conditionReasonValid, "Schedules are valid")
+	if cronhpa.IsSuspended() {
+		// Keep the schedules so that the cron jobs keep the next schedules in the status.
+		if err := cronhpa.UpdateSuspendedStatus(ctx, r); err != nil {
+			return ctrl.Result{}, err
+		}
+	} else if err := cronhpa.CreateOrPatchHPA(ctx, patchName, now, r); err != nil {
+		return ctrl.Result{}, cronhpa.UpdateConditionWithError(ctx, r, cronhpav1beta1.ConditionTypeHPASynced, conditionReasonSyncFailed, err)
 	}
 
 	// Update the schedules.
 	logger.Info("Update schedules")
 	if err := cronhpa.UpdateSchedules(ctx, r); err != nil {
+		return ctrl.Result{}, cronhpa.UpdateConditionWithError(ctx, r, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidSchedule, err)
+	}
+
+	if err := cronhpa.UpdateCompletedPatches(ctx, now, r); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Requeue to apply the dated patch, to revert the patch when the active window closes,
+	// or to resume updating the target when the skip-until annotation expires.
+	// Cron jobs starting windows update the status, which triggers another reconciliation.
+	nextTime, err := cronhpa.GetNextReconcileTime(now, calendar)
+	if err != nil {
 		return ctrl.Result{}, err
 	}
+	if !nextTime.IsZero() {
+		logger.Info(fmt.Sprintf("Requeue at %s", nextTime))
+		return ctrl.Result{RequeueAfter: nextTime.Sub(now)}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
 
+// GetHPAGroupVersion returns the API version of HPAs to manage.
+func (r *CronHorizontalPodAutoscalerReconciler) GetHPAGroupVersion() schema.GroupVersion {
+	if r.HPAGroupVersion.Empty() {
+		return autoscalingv2.SchemeGroupVersion
+	}
+	return r.HPAGroupVersion
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *CronHorizontalPodAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	hpa, err := NewVersionedHPA(r.GetHPAGroupVersion())
+	if err != nil {
+		return err
+	}
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &cronhpav1beta1.CronHorizontalPodAutoscaler{}, configMapRefIndexKey, indexConfigMapRef); err != nil {
+		return err
+	}
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&cronhpav1alpha1.CronHorizontalPodAutoscaler{}).
-		Owns(&autoscalingv2beta2.HorizontalPodAutoscaler{}).
+		For(&cronhpav1beta1.CronHorizontalPodAutoscaler{}).
+		Owns(hpa).
+		Watches(
+			&source.Kind{Type: hpa},
+			handler.EnqueueRequestsFromMapFunc(r.findCronHPAsForHPA),
+			// Ignore the status updates of the HPAs by the HPA controller.
+			builder.WithPredicates(predicate.Or(predicate.GenerationChangedPredicate{}, predicate.LabelChangedPredicate{})),
+		).
+		// Only the metadata of the ConfigMaps is cached, and the referenced ones are read from the API server.
+		Watches(
+			&source.Kind{Type: &corev1.ConfigMap{}},
+			handler.EnqueueRequestsFromMapFunc(r.findCronHPAsForConfigMap),
+			builder.OnlyMetadata,
+		).
 		Complete(r)
 }
+
+// indexConfigMapRef returns the name of the ConfigMap referred by the exclusion calendar of the CronHPA.
+func indexConfigMapRef(obj client.Object) []string {
+	cronhpa, ok := obj.(*cronhpav1beta1.CronHorizontalPodAutoscaler)
+	if !ok {
+		return nil
+	}
+	calendar := cronhpa.Spec.ExclusionCalendar
+	if calendar == nil || calendar.ConfigMapRef == nil {
+		return nil
+	}
+	return []string{calendar.ConfigMapRef.Name}
+}
+
+// findCronHPAsForHPA returns the requests of the CronHPAs adopting the HPA by hpaRef or hpaSelector,
+// so that they react when the HPAs appear, change or disappear.
+func (r *CronHorizontalPodAutoscalerReconciler) findCronHPAsForHPA(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	logger := log.FromContext(ctx)
+
+	if isControlledByCronHPA(obj) {
+		return nil
+	}
+	cronhpas := &cronhpav1beta1.CronHorizontalPodAutoscalerList{}
+	if err := r.List(ctx, cronhpas, client.InNamespace(obj.GetNamespace())); err != nil {
+		logger.Error(err, "Failed to list CronHPAs")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0)
+	for i := range cronhpas.Items {
+		cronhpa := (*CronHorizontalPodAutoscaler)(&cronhpas.Items[i])
+		if cronhpa.selects(obj) {
+			requests = append(requests, reconcile.Request{NamespacedName: cronhpa.ToNamespacedName()})
+		}
+	}
+	return requests
+}
+
+// findCronHPAsForConfigMap returns the requests of the CronHPAs referring to the ConfigMap as their exclusion calendar.
+func (r *CronHorizontalPodAutoscalerReconciler) findCronHPAsForConfigMap(obj client.Object) []reconcile.Request {
+	ctx := context.Background()
+	logger := log.FromContext(ctx)
+
+	cronhpas := &cronhpav1beta1.CronHorizontalPodAutoscalerList{}
+	if err := r.List(ctx, cronhpas, client.InNamespace(obj.GetNamespace()), client.MatchingFields{configMapRefIndexKey: obj.GetName()}); err != nil {
+		logger.Error(err, "Failed to list CronHPAs")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(cronhpas.Items))
+	for _, cronhpa := range cronhpas.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: cronhpa.Namespace, Name: cronhpa.Name}})
+	}
+	return requests
+}
@@ -55,6 +55,15 @@ func (c *Cron) Stop() {
 }
 
 func (c *Cron) Add(namespacedName types.NamespacedName, patchName, tzs string, job cron.Job) error {
+	schedule, err := standardParser.Parse(tzs)
+	if err != nil {
+		return err
+	}
+	c.AddSchedule(namespacedName, patchName, schedule, job)
+	return nil
+}
+
+func (c *Cron) AddSchedule(namespacedName types.NamespacedName, patchName string, schedule cron.Schedule, job cron.Job) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
@@ -64,12 +73,7 @@ func (c *Cron) Add(namespacedName types.NamespacedName, patchName, tzs string, j
 		resourceEntry = make(map[string]cron.EntryID)
 		c.resourceEntries[resourceName] = resourceEntry
 	}
-	entryID, err := c.cron.AddJob(tzs, job)
-	if err != nil {
-		return err
-	}
-	resourceEntry[patchName] = entryID
-	return nil
+	resourceEntry[patchName] = c.cron.Schedule(schedule, job)
 }
 
 func (c *Cron) Remove(namespacedName types.NamespacedName, patchName string) {
@@ -18,10 +18,14 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
 )
 
 type CronContext struct {
@@ -57,10 +61,51 @@ func (cronctx *CronContext) run(ctx context.Context) error {
 		}
 		return err
 	}
+	cronhpa.SetDefaultTimezones(cronctx.reconciler.DefaultTimezone)
 
-	if err := cronhpa.CreateOrPatchHPA(ctx, cronctx.patchName, now, cronctx.reconciler); err != nil {
+	calendar, err := cronhpa.GetExclusionCalendar(ctx, cronctx.reconciler)
+	if err != nil {
+		return cronhpa.UpdateConditionWithError(ctx, cronctx.reconciler, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidExclusionCalendar, err)
+	}
+	if cronhpa.IsSuspended() {
+		if err := cronhpa.SetNextSchedules(now, calendar); err != nil {
+			return cronhpa.UpdateConditionWithError(ctx, cronctx.reconciler, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidSchedule, err)
+		}
+		return cronhpa.UpdateSuspendedStatus(ctx, cronctx.reconciler)
+	}
+	excluded, err := cronhpa.IsExcluded(cronctx.patchName, now, calendar)
+	if err != nil {
 		return err
 	}
+	if excluded {
+		logger.Info("Skip a cron job on an excluded date")
+		if err := cronhpa.SetNextSchedules(now, calendar); err != nil {
+			return err
+		}
+		if err := cronctx.reconciler.Status().Update(ctx, cronhpa.ToCompatible()); err != nil {
+			return err
+		}
+		msg := fmt.Sprintf("Skipped %s on an excluded date", cronctx.patchName)
+		cronctx.reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeNormal, CronHPAEventSkipped, msg)
+		return nil
+	}
+
+	patchName, err := cronhpa.GetFiredPatchName(ctx, cronctx.patchName, now, calendar)
+	if err != nil {
+		return cronhpa.UpdateConditionWithError(ctx, cronctx.reconciler, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidSchedule, err)
+	}
+	if patchName != cronctx.patchName {
+		logger.Info(fmt.Sprintf("Skip %s overridden by %s", cronctx.patchName, patchName))
+		msg := fmt.Sprintf("Skipped %s overridden by %s with a higher priority", cronctx.patchName, patchName)
+		cronctx.reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeNormal, CronHPAEventSkipped, msg)
+	}
+
+	if err := cronhpa.SetNextSchedules(now, calendar); err != nil {
+		return cronhpa.UpdateConditionWithError(ctx, cronctx.reconciler, cronhpav1beta1.ConditionTypeSchedulesValid, conditionReasonInvalidSchedule, err)
+	}
+	if err := cronhpa.CreateOrPatchHPA(ctx, patchName, now, cronctx.reconciler); err != nil {
+		return cronhpa.UpdateConditionWithError(ctx, cronctx.reconciler, cronhpav1beta1.ConditionTypeHPASynced, conditionReasonSyncFailed, err)
+	}
 
 	return nil
 }
@@ -0,0 +1,133 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	"github.com/dtaniwaki/cron-hpa/test"
+)
+
+func TestCronContextRunWithSuspend(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  suspend: true
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 5
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, clientgoscheme.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cronhpa.ToCompatible()).Build()
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:   fakeClient,
+		Recorder: &test.FakeRecorder{},
+		Cron:     NewCron(),
+	}
+	cronctx := &CronContext{
+		reconciler: reconciler,
+		cronhpa:    cronhpa,
+		patchName:  "daytime",
+	}
+
+	// Suspended by the spec.
+	err = cronctx.run(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	err = fakeClient.Get(ctx, cronhpa.ToHPANamespacedName(), hpa)
+	if !assert.True(t, errors.IsNotFound(err)) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "daytime", cronhpa.Status.NextPatchName) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "", cronhpa.Status.LastScheduledPatchName) {
+		t.FailNow()
+	}
+	condition := meta.FindStatusCondition(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeSuspended)
+	if !assert.Equal(t, conditionReasonSuspended, condition.Reason) {
+		t.FailNow()
+	}
+
+	// Resumed.
+	suspend := false
+	cronhpa.Spec.Suspend = &suspend
+	err = fakeClient.Update(ctx, cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = cronctx.run(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = fakeClient.Get(ctx, cronhpa.ToHPANamespacedName(), hpa)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(5), *hpa.Spec.MinReplicas) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "daytime", cronhpa.Status.LastScheduledPatchName) {
+		t.FailNow()
+	}
+	if !assert.True(t, meta.IsStatusConditionFalse(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeSuspended)) {
+		t.FailNow()
+	}
+}
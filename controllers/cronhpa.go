@@ -18,40 +18,70 @@ package controllers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strings"
 	"time"
 
-	"github.com/dtaniwaki/cron-hpa/api/v1alpha1"
-	cronhpav1alpha1 "github.com/dtaniwaki/cron-hpa/api/v1alpha1"
-	"github.com/robfig/cron/v3"
-	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	jsonpatch "github.com/evanphx/json-patch"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
-type CronHorizontalPodAutoscaler cronhpav1alpha1.CronHorizontalPodAutoscaler
+type CronHorizontalPodAutoscaler cronhpav1beta1.CronHorizontalPodAutoscaler
 
 type CronHPAEvent = string
 
-const annotationNameSkip = "cron-hpa.dtaniwaki.github.com/skip"
+const (
+	annotationNameSkip      = "cron-hpa.dtaniwaki.github.com/skip"
+	annotationNameSkipUntil = "cron-hpa.dtaniwaki.github.com/skip-until"
+
+	annotationNameTrigger         = "cron-hpa.dtaniwaki.github.com/trigger"
+	annotationNameTriggerDuration = "cron-hpa.dtaniwaki.github.com/trigger-duration"
+)
 
 const (
-	CronHPAEventCreated     CronHPAEvent = "Created"
-	CronHPAEventUpdated     CronHPAEvent = "Updated"
-	CronHPAEventScheduled   CronHPAEvent = "Scheduled"
-	CronHPAEventUnscheduled CronHPAEvent = "Unscheduled"
-	CronHPAEventSkipped     CronHPAEvent = "Skipped"
-	CronHPAEventNone        CronHPAEvent = ""
+	conditionReasonValid                    = "Valid"
+	conditionReasonInvalidSchedule          = "InvalidSchedule"
+	conditionReasonInvalidExclusionCalendar = "InvalidExclusionCalendar"
+	conditionReasonCreated                  = "Created"
+	conditionReasonUpdated                  = "Updated"
+	conditionReasonNoChanges                = "NoChanges"
+	conditionReasonSkipped                  = "Skipped"
+	conditionReasonSyncFailed               = "SyncFailed"
+	conditionReasonSkipAnnotation           = "SkipAnnotation"
+	conditionReasonNotSuspended             = "NotSuspended"
+	conditionReasonNoTargets                = "NoTargets"
+	conditionReasonSuspended                = "Suspended"
+	conditionReasonScaleDownDeferred        = "ScaleDownDeferred"
+	conditionReasonScaleDownClamped         = "ScaleDownClamped"
 )
 
-const MAX_SCHEDULE_TRY = 1000000
+const (
+	CronHPAEventCreated          CronHPAEvent = "Created"
+	CronHPAEventUpdated          CronHPAEvent = "Updated"
+	CronHPAEventScheduled        CronHPAEvent = "Scheduled"
+	CronHPAEventUnscheduled      CronHPAEvent = "Unscheduled"
+	CronHPAEventSkipped          CronHPAEvent = "Skipped"
+	CronHPAEventCompleted        CronHPAEvent = "Completed"
+	CronHPAEventSuspended        CronHPAEvent = "Suspended"
+	CronHPAEventSkipExpired      CronHPAEvent = "SkipExpired"
+	CronHPAEventTriggered        CronHPAEvent = "Triggered"
+	CronHPAEventTriggerFailed    CronHPAEvent = "TriggerFailed"
+	CronHPAEventScaleDownGuarded CronHPAEvent = "ScaleDownGuarded"
+	CronHPAEventMissed           CronHPAEvent = "Missed"
+	CronHPAEventNone             CronHPAEvent = ""
+)
 
 func (cronhpa *CronHorizontalPodAutoscaler) UpdateSchedules(ctx context.Context, reconciler *CronHorizontalPodAutoscalerReconciler) error {
 	logger := log.FromContext(ctx)
@@ -61,34 +91,37 @@ func (cronhpa *CronHorizontalPodAutoscaler) UpdateSchedules(ctx context.Context,
 	entryNames := make([]string, 0)
 	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
 		entryNames = append(entryNames, scheduledPatch.Name)
-		tzs := scheduledPatch.Schedule
-		if scheduledPatch.Timezone != "" {
-			tzs = "CRON_TZ=" + scheduledPatch.Timezone + " " + scheduledPatch.Schedule
+		if isDatedPatch(&scheduledPatch) {
+			// Dated patches are applied by requeueing the reconciliation at their start time.
+			logger.Info(fmt.Sprintf("Scheduled %s at %s", scheduledPatch.Name, scheduledPatch.StartTime))
+			continue
 		}
-		err := reconciler.Cron.Add(cronhpa.ToNamespacedName(), scheduledPatch.Name, tzs, &CronContext{
+		// The excluded dates are checked when the patch fires.
+		schedule, err := parseStartSchedule(&scheduledPatch, nil)
+		if err != nil {
+			return err
+		}
+		reconciler.Cron.AddSchedule(cronhpa.ToNamespacedName(), scheduledPatch.Name, schedule, &CronContext{
 			reconciler: reconciler,
 			cronhpa:    cronhpa,
 			patchName:  scheduledPatch.Name,
 		})
-		if err != nil {
-			return err
-		}
 		logger.Info(fmt.Sprintf("Scheduled %s", scheduledPatch.Name))
 	}
 	msg := fmt.Sprintf("Scheduled: %s", strings.Join(entryNames, ","))
-	reconciler.Recorder.Event((*cronhpav1alpha1.CronHorizontalPodAutoscaler)(cronhpa), corev1.EventTypeNormal, CronHPAEventScheduled, msg)
+	reconciler.Recorder.Event((*cronhpav1beta1.CronHorizontalPodAutoscaler)(cronhpa), corev1.EventTypeNormal, CronHPAEventScheduled, msg)
 	return nil
 }
 
 func (cronhpa *CronHorizontalPodAutoscaler) ClearSchedules(ctx context.Context, reconciler *CronHorizontalPodAutoscalerReconciler) error {
 	reconciler.Cron.RemoveResourceEntry(cronhpa.ToNamespacedName())
 	msg := "Unscheduled"
-	reconciler.Recorder.Event((*cronhpav1alpha1.CronHorizontalPodAutoscaler)(cronhpa), corev1.EventTypeNormal, CronHPAEventUnscheduled, msg)
+	reconciler.Recorder.Event((*cronhpav1beta1.CronHorizontalPodAutoscaler)(cronhpa), corev1.EventTypeNormal, CronHPAEventUnscheduled, msg)
 	return nil
 }
 
-func (cronhpa *CronHorizontalPodAutoscaler) ApplyHPAPatch(patchName string, hpa *autoscalingv2beta2.HorizontalPodAutoscaler) error {
-	var scheduledPatch *cronhpav1alpha1.CronHorizontalPodAutoscalerScheduledPatch
+func (cronhpa *CronHorizontalPodAutoscaler) ApplyHPAPatch(patchName string, hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+	var scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch
 	for _, sp := range cronhpa.Spec.ScheduledPatches {
 		if sp.Name == patchName {
 			scheduledPatch = &sp
@@ -101,28 +134,169 @@ func (cronhpa *CronHorizontalPodAutoscaler) ApplyHPAPatch(patchName string, hpa
 
 	// Apply patches on the template.
 	if scheduledPatch.Patch != nil {
-		if scheduledPatch.Patch.MinReplicas != nil {
-			*hpa.Spec.MinReplicas = *scheduledPatch.Patch.MinReplicas
-		}
-		if scheduledPatch.Patch.MaxReplicas != nil {
-			hpa.Spec.MaxReplicas = *scheduledPatch.Patch.MaxReplicas
+		if err := applyReplicasPatch(scheduledPatch.Patch, hpa); err != nil {
+			return fmt.Errorf("Failed to apply replicas of %s: %w", patchName, err)
 		}
-		if scheduledPatch.Patch.Metrics != nil {
-			hpa.Spec.Metrics = make([]autoscalingv2beta2.MetricSpec, len(scheduledPatch.Patch.Metrics))
+		if scheduledPatch.Patch.MetricsMergeMode == cronhpav1beta1.MetricsMergeModeMerge {
+			hpa.Spec.Metrics = mergeHPAMetrics(hpa.Spec.Metrics, scheduledPatch.Patch)
+		} else if scheduledPatch.Patch.Metrics != nil {
+			hpa.Spec.Metrics = make([]autoscalingv2.MetricSpec, len(scheduledPatch.Patch.Metrics))
 			for i, metric := range scheduledPatch.Patch.Metrics {
 				hpa.Spec.Metrics[i] = metric
 			}
 		}
+		if scheduledPatch.Patch.Behavior != nil {
+			hpa.Spec.Behavior = mergeHPABehavior(hpa.Spec.Behavior, scheduledPatch.Patch.Behavior)
+		}
+	}
+
+	// Apply generic patches on the patched template.
+	if scheduledPatch.StrategicMergePatch != nil || scheduledPatch.JSONPatch != nil {
+		if err := applyGenericHPAPatches(scheduledPatch, hpa); err != nil {
+			return fmt.Errorf("Failed to apply patches of %s: %w", patchName, err)
+		}
+	}
+	return nil
+}
+
+// Generic patches can change only the spec and the labels and annotations of HPA.
+var genericHPAPatchablePaths = []string{"/spec", "/metadata/labels", "/metadata/annotations"}
+
+func applyGenericHPAPatches(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+	data, err := json.Marshal(hpa)
+	if err != nil {
+		return err
+	}
+
+	if scheduledPatch.StrategicMergePatch != nil {
+		patch := map[string]interface{}{}
+		if err := json.Unmarshal(scheduledPatch.StrategicMergePatch.Raw, &patch); err != nil {
+			return fmt.Errorf("Invalid strategic merge patch: %w", err)
+		}
+		for key, value := range patch {
+			if key == "metadata" {
+				metadata, ok := value.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("Invalid strategic merge patch: metadata must be an object")
+				}
+				for metadataKey := range metadata {
+					if !isGenericHPAPatchablePath("/metadata/" + metadataKey) {
+						return fmt.Errorf("Invalid strategic merge patch: metadata.%s cannot be patched", metadataKey)
+					}
+				}
+			} else if !isGenericHPAPatchablePath("/" + key) {
+				return fmt.Errorf("Invalid strategic merge patch: %s cannot be patched", key)
+			}
+		}
+		data, err = strategicpatch.StrategicMergePatch(data, scheduledPatch.StrategicMergePatch.Raw, autoscalingv2.HorizontalPodAutoscaler{})
+		if err != nil {
+			return fmt.Errorf("Invalid strategic merge patch: %w", err)
+		}
+	}
+
+	if scheduledPatch.JSONPatch != nil {
+		patch, err := decodeGenericHPAJSONPatch(scheduledPatch.JSONPatch)
+		if err != nil {
+			return err
+		}
+		data, err = patch.Apply(data)
+		if err != nil {
+			return fmt.Errorf("Invalid JSON patch: %w", err)
+		}
 	}
+
+	patched := &autoscalingv2.HorizontalPodAutoscaler{}
+	if err := json.Unmarshal(data, patched); err != nil {
+		return err
+	}
+	hpa.ObjectMeta.Labels = patched.ObjectMeta.Labels
+	hpa.ObjectMeta.Annotations = patched.ObjectMeta.Annotations
+	hpa.Spec = patched.Spec
 	return nil
 }
 
-func (cronhpa *CronHorizontalPodAutoscaler) NewHPA(patchName string) (*autoscalingv2beta2.HorizontalPodAutoscaler, error) {
+// decodeGenericHPAJSONPatch decodes the JSON patch operations which change only the patchable paths of HPA.
+func decodeGenericHPAJSONPatch(operations []cronhpav1beta1.JSONPatchOperation) (jsonpatch.Patch, error) {
+	for i, operation := range operations {
+		if !isGenericHPAPatchablePath(operation.Path) {
+			return nil, fmt.Errorf("Invalid JSON patch: %s of operation %d cannot be patched", operation.Path, i)
+		}
+		if (operation.Op == "move" || operation.Op == "copy") && !isGenericHPAPatchablePath(operation.From) {
+			return nil, fmt.Errorf("Invalid JSON patch: %s of operation %d cannot be patched", operation.From, i)
+		}
+	}
+	patchData, err := json.Marshal(operations)
+	if err != nil {
+		return nil, err
+	}
+	patch, err := jsonpatch.DecodePatch(patchData)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid JSON patch: %w", err)
+	}
+	return patch, nil
+}
+
+func isGenericHPAPatchablePath(path string) bool {
+	for _, patchablePath := range genericHPAPatchablePaths {
+		if path == patchablePath || strings.HasPrefix(path, patchablePath+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeHPABehavior(behavior, patch *autoscalingv2.HorizontalPodAutoscalerBehavior) *autoscalingv2.HorizontalPodAutoscalerBehavior {
+	merged := &autoscalingv2.HorizontalPodAutoscalerBehavior{}
+	if behavior != nil {
+		merged = behavior.DeepCopy()
+	}
+	merged.ScaleUp = mergeHPAScalingRules(merged.ScaleUp, patch.ScaleUp)
+	merged.ScaleDown = mergeHPAScalingRules(merged.ScaleDown, patch.ScaleDown)
+	return merged
+}
+
+func mergeHPAScalingRules(rules, patch *autoscalingv2.HPAScalingRules) *autoscalingv2.HPAScalingRules {
+	if patch == nil {
+		return rules
+	}
+	if rules == nil {
+		return patch.DeepCopy()
+	}
+	merged := rules.DeepCopy()
+	if patch.StabilizationWindowSeconds != nil {
+		stabilizationWindowSeconds := *patch.StabilizationWindowSeconds
+		merged.StabilizationWindowSeconds = &stabilizationWindowSeconds
+	}
+	if patch.SelectPolicy != nil {
+		selectPolicy := *patch.SelectPolicy
+		merged.SelectPolicy = &selectPolicy
+	}
+	if patch.Policies != nil {
+		// Replace the policies of the same types with the patched ones.
+		patchedTypes := make(map[autoscalingv2.HPAScalingPolicyType]bool)
+		for _, policy := range patch.Policies {
+			patchedTypes[policy.Type] = true
+		}
+		policies := make([]autoscalingv2.HPAScalingPolicy, 0, len(merged.Policies)+len(patch.Policies))
+		for _, policy := range merged.Policies {
+			if !patchedTypes[policy.Type] {
+				policies = append(policies, policy)
+			}
+		}
+		merged.Policies = append(policies, patch.Policies...)
+	}
+	return merged
+}
+
+func (cronhpa *CronHorizontalPodAutoscaler) NewHPA(patchName string) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	if cronhpa.Spec.Template == nil {
+		return nil, fmt.Errorf("No template of HPA")
+	}
 	template := cronhpa.Spec.Template.DeepCopy()
-	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "HorizontalPodAutoscaler",
-			APIVersion: autoscalingv2beta2.SchemeGroupVersion.Identifier(),
+			APIVersion: autoscalingv2.SchemeGroupVersion.Identifier(),
 		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      cronhpa.Name,
@@ -142,59 +316,338 @@ func (cronhpa *CronHorizontalPodAutoscaler) NewHPA(patchName string) (*autoscali
 	return hpa, nil
 }
 
-func (cronhpa *CronHorizontalPodAutoscaler) GetCurrentPatchName(ctx context.Context, currentTime time.Time) (string, error) {
+// NewAdoptedHPA returns the adopted HPA with the baseline spec and the patch.
+// The metadata of the HPA is kept as is except for the changes by the patch.
+func (cronhpa *CronHorizontalPodAutoscaler) NewAdoptedHPA(patchName string, hpa *autoscalingv2.HorizontalPodAutoscaler) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	return cronhpa.newAdoptedHPA(patchName, cronhpa.Status.BaselineHPASpec, hpa)
+}
+
+func (cronhpa *CronHorizontalPodAutoscaler) newAdoptedHPA(patchName string, baseline *autoscalingv2.HorizontalPodAutoscalerSpec, hpa *autoscalingv2.HorizontalPodAutoscaler) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	if baseline == nil {
+		return nil, fmt.Errorf("No baseline of HPA %s", hpa.Name)
+	}
+	newhpa := hpa.DeepCopy()
+	newhpa.Spec = *baseline.DeepCopy()
+	if patchName != "" {
+		if err := cronhpa.ApplyHPAPatch(patchName, newhpa); err != nil {
+			return nil, err
+		}
+	}
+	return newhpa, nil
+}
+
+// UpdateBaselineHPASpec captures the spec of the adopted HPA as the baseline when it's adopted first,
+// or when it's changed by others while no patch is applied. It returns true if the baseline changes.
+func (cronhpa *CronHorizontalPodAutoscaler) UpdateBaselineHPASpec(hpa *autoscalingv2.HorizontalPodAutoscaler) bool {
+	if !shouldCaptureBaseline(cronhpa.Status.BaselineHPASpec, cronhpa.Status.LastScheduledPatchName, hpa) {
+		return false
+	}
+	cronhpa.Status.BaselineHPASpec = hpa.Spec.DeepCopy()
+	return true
+}
+
+// shouldCaptureBaseline returns true if the spec of the HPA should be the new baseline.
+// The HPA applied with a patch cannot tell its baseline.
+func shouldCaptureBaseline(baseline *autoscalingv2.HorizontalPodAutoscalerSpec, appliedPatchName string, hpa *autoscalingv2.HorizontalPodAutoscaler) bool {
+	return baseline == nil || (appliedPatchName == "" && !reflect.DeepEqual(*baseline, hpa.Spec))
+}
+
+// RestoreBaselineHPA restores the baseline specs of the adopted HPAs, or the ScaledObject.
+func (cronhpa *CronHorizontalPodAutoscaler) RestoreBaselineHPA(ctx context.Context, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	if cronhpa.Spec.ScaledObjectRef != nil {
+		return cronhpa.restoreBaselineScaledObject(ctx, reconciler)
+	}
+	if cronhpa.Spec.HPASelector != nil {
+		for _, target := range cronhpa.Status.Targets {
+			name := types.NamespacedName{Namespace: cronhpa.Namespace, Name: target.Name}
+			if err := cronhpa.restoreBaselineHPA(ctx, name, target.BaselineHPASpec, reconciler); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if cronhpa.Spec.HPARef == nil {
+		return nil
+	}
+	return cronhpa.restoreBaselineHPA(ctx, cronhpa.ToHPANamespacedName(), cronhpa.Status.BaselineHPASpec, reconciler)
+}
+
+func (cronhpa *CronHorizontalPodAutoscaler) restoreBaselineHPA(ctx context.Context, name types.NamespacedName, baseline *autoscalingv2.HorizontalPodAutoscalerSpec, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	logger := log.FromContext(ctx)
+
+	if baseline == nil {
+		return nil
+	}
+	obj, err := NewVersionedHPA(reconciler.GetHPAGroupVersion())
+	if err != nil {
+		return err
+	}
+	if err := reconciler.Get(ctx, name, obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	hpa, err := FromVersionedHPA(obj)
+	if err != nil {
+		return err
+	}
+	newhpa, err := cronhpa.newAdoptedHPA("", baseline, hpa)
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(hpa.Spec, newhpa.Spec) {
+		return nil
+	}
+	newobj, err := ToVersionedHPA(newhpa, reconciler.GetHPAGroupVersion())
+	if err != nil {
+		return err
+	}
+	if err := reconciler.Patch(ctx, newobj, client.MergeFrom(obj)); err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Restored the baseline of HPA %s", name.Name))
+	return nil
+}
+
+// getScheduledPatch returns the scheduled patch with the name, or nil if there is none.
+func (cronhpa *CronHorizontalPodAutoscaler) getScheduledPatch(patchName string) *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch {
+	for i := range cronhpa.Spec.ScheduledPatches {
+		if cronhpa.Spec.ScheduledPatches[i].Name == patchName {
+			return &cronhpa.Spec.ScheduledPatches[i]
+		}
+	}
+	return nil
+}
+
+// GetCurrentPatchName returns the name of the patch which should be applied at the given time.
+// Point patches are caught up from the last cron timestamp, and window patches are active until their windows close.
+// The active patch with the highest priority wins, the most recently started one wins among the same priority,
+// and the template is used if none is active. Fire times on the dates excluded by the calendar are ignored.
+func (cronhpa *CronHorizontalPodAutoscaler) GetCurrentPatchName(ctx context.Context, currentTime time.Time, calendar ExclusionCalendar) (string, error) {
 	logger := log.FromContext(ctx)
 
 	logger.Info("Get current patch")
+	currentPatch, err := cronhpa.getCurrentPatch(ctx, currentTime, calendar)
+	if err != nil {
+		return "", err
+	}
 	currentPatchName := ""
+	if currentPatch != nil {
+		currentPatchName = currentPatch.name
+	}
+	if cronhpa.Status.LastScheduledPatchName != currentPatchName {
+		logger.Info(fmt.Sprintf("Current patch changed from %s to %s", cronhpa.Status.LastScheduledPatchName, currentPatchName))
+	}
+	return currentPatchName, nil
+}
+
+// GetFiredPatchName returns the name of the patch which should be applied when the patch fires at the given time.
+// The fired patch is ignored if an active patch has a higher priority.
+func (cronhpa *CronHorizontalPodAutoscaler) GetFiredPatchName(ctx context.Context, patchName string, currentTime time.Time, calendar ExclusionCalendar) (string, error) {
+	currentPatch, err := cronhpa.getCurrentPatch(ctx, currentTime, calendar)
+	if err != nil {
+		return "", err
+	}
 	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
-		if scheduledPatch.Name == cronhpa.Status.LastScheduledPatchName {
-			currentPatchName = scheduledPatch.Name
+		if scheduledPatch.Name == patchName {
+			firedPatch := &activePatch{name: patchName, priority: scheduledPatch.Priority, time: currentTime}
+			if firedPatch.overrides(currentPatch) {
+				currentPatch = firedPatch
+			}
 			break
 		}
 	}
-	if cronhpa.Status.LastScheduledPatchName != "" && currentPatchName == "" {
-		logger.Info(fmt.Sprintf("Lost scheduled patch %s", cronhpa.Status.LastScheduledPatchName))
+	if currentPatch == nil {
+		return "", nil
 	}
+	return currentPatch.name, nil
+}
+
+func (cronhpa *CronHorizontalPodAutoscaler) getCurrentPatch(ctx context.Context, currentTime time.Time, calendar ExclusionCalendar) (*activePatch, error) {
+	logger := log.FromContext(ctx)
+
+	var currentPatch *activePatch
+	lastScheduledPatchName := cronhpa.Status.LastScheduledPatchName
 	lastCronTimestamp := cronhpa.Status.LastCronTimestamp
-	if lastCronTimestamp != nil {
-		var standardParser = cron.NewParser(
-			cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
-		)
-
-		mostLatestTime := lastCronTimestamp.Time
-		for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
-			tzs := scheduledPatch.Schedule
-			if scheduledPatch.Timezone != "" {
-				tzs = "CRON_TZ=" + scheduledPatch.Timezone + " " + scheduledPatch.Schedule
+	// The last scheduled patch is the triggered one once the trigger is applied, so the scheduled patches are
+	// found from their latest schedules regardless of the last cron timestamp after the trigger ends.
+	unbounded := cronhpa.isTriggerEnded(currentTime)
+	if unbounded {
+		lastScheduledPatchName = ""
+	}
+	found := false
+	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		if scheduledPatch.Name == lastScheduledPatchName {
+			found = true
+			if isWindowPatch(&scheduledPatch) {
+				// Window patches are evaluated by their windows below. The patches without windows which fired
+				// while the window was applied are found from their latest schedules, since the last cron timestamp
+				// has passed them, so that they take over when the window closes.
+				unbounded = true
+			} else {
+				currentPatch = &activePatch{name: scheduledPatch.Name, priority: scheduledPatch.Priority}
+				if lastCronTimestamp != nil {
+					currentPatch.time = lastCronTimestamp.Time
+				}
 			}
-			schedule, err := standardParser.Parse(tzs)
-			if err != nil {
-				return "", err
+			break
+		}
+	}
+	if lastScheduledPatchName != "" && !found {
+		logger.Info(fmt.Sprintf("Lost scheduled patch %s", lastScheduledPatchName))
+	}
+	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		scheduledPatch := scheduledPatch
+		var latestTime time.Time
+		var err error
+		if unbounded {
+			latestTime, err = findLatestStartTime(&scheduledPatch, currentTime, calendar)
+		} else {
+			latestTime, err = getLatestStartTime(&scheduledPatch, lastCronTimestamp, currentTime, calendar)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if latestTime.IsZero() {
+			continue
+		}
+		patchName := scheduledPatch.Name
+		if cronhpa.isMissedPatch(patchName, latestTime) {
+			switch getMissedSchedulePolicy(&scheduledPatch) {
+			case cronhpav1beta1.MissedSchedulePolicySkipIfMissed:
+				continue
+			case cronhpav1beta1.MissedSchedulePolicyApplyTemplate:
+				patchName = ""
 			}
-			nextTime := lastCronTimestamp.Time
-			latestTime := lastCronTimestamp.Time
-			for i := 0; i <= MAX_SCHEDULE_TRY; i++ {
-				nextTime = schedule.Next(nextTime)
-				if nextTime.After(currentTime) || nextTime.IsZero() {
-					break
-				}
-				latestTime = nextTime
-				if i == MAX_SCHEDULE_TRY {
-					return "", fmt.Errorf("Cannot find the next schedule of patch %s", scheduledPatch.Name)
-				}
+		}
+		patch := &activePatch{name: patchName, priority: scheduledPatch.Priority, time: latestTime}
+		if patch.overrides(currentPatch) {
+			currentPatch = patch
+		}
+	}
+	if triggeredPatch := cronhpa.getTriggeredPatch(ctx, currentPatch, currentTime); triggeredPatch != nil {
+		return triggeredPatch, nil
+	}
+	return currentPatch, nil
+}
+
+// GetNextReconcileTime returns the earliest time when a dated patch starts, an active window closes,
+// the skip-until annotation expires, the triggered patch ends, the ramp steps or the guarded patch is retried,
+// or the zero time if there is none.
+func (cronhpa *CronHorizontalPodAutoscaler) GetNextReconcileTime(currentTime time.Time, calendar ExclusionCalendar) (time.Time, error) {
+	nextTime := time.Time{}
+	if cronhpa.Status.SkipUntil != nil && cronhpa.Status.SkipUntil.After(currentTime) {
+		nextTime = cronhpa.Status.SkipUntil.Time
+	}
+	if triggeredPatch := cronhpa.Status.TriggeredPatch; triggeredPatch != nil && triggeredPatch.EndTime != nil && triggeredPatch.EndTime.After(currentTime) {
+		if nextTime.IsZero() || triggeredPatch.EndTime.Before(&metav1.Time{Time: nextTime}) {
+			nextTime = triggeredPatch.EndTime.Time
+		}
+	}
+	if guardedPatch := cronhpa.Status.GuardedPatch; guardedPatch != nil && guardedPatch.RetryTime.After(currentTime) {
+		if nextTime.IsZero() || guardedPatch.RetryTime.Time.Before(nextTime) {
+			nextTime = guardedPatch.RetryTime.Time
+		}
+	}
+	if rampTime := cronhpa.getNextRampTime(currentTime); !rampTime.IsZero() && (nextTime.IsZero() || rampTime.Before(nextTime)) {
+		nextTime = rampTime
+	}
+	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		scheduledPatch := scheduledPatch
+		if !isWindowPatch(&scheduledPatch) {
+			continue
+		}
+		_, endTime, err := getActiveWindow(&scheduledPatch, currentTime, calendar)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if isDatedPatch(&scheduledPatch) && getDatedStartTime(&scheduledPatch).After(currentTime) {
+			endTime = getDatedStartTime(&scheduledPatch)
+		}
+		if !endTime.IsZero() && (nextTime.IsZero() || endTime.Before(nextTime)) {
+			nextTime = endTime
+		}
+	}
+	return nextTime, nil
+}
+
+// SetNextSchedules sets the next fire time of each scheduled patch and the earliest one in the status.
+func (cronhpa *CronHorizontalPodAutoscaler) SetNextSchedules(currentTime time.Time, calendar ExclusionCalendar) error {
+	scheduledPatchStatuses := make([]cronhpav1beta1.ScheduledPatchStatus, 0, len(cronhpa.Spec.ScheduledPatches))
+	nextPatchName := ""
+	var nextTime *metav1.Time
+	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		scheduledPatch := scheduledPatch
+		scheduledPatchStatus := cronhpav1beta1.ScheduledPatchStatus{Name: scheduledPatch.Name}
+		if isDatedPatch(&scheduledPatch) {
+			if getDatedStartTime(&scheduledPatch).After(currentTime) {
+				scheduledPatchStatus.NextTime = scheduledPatch.StartTime.DeepCopy()
 			}
-			if latestTime.After(mostLatestTime) && (latestTime.Before(currentTime) || latestTime.Equal(currentTime)) {
-				currentPatchName = scheduledPatch.Name
-				mostLatestTime = latestTime
+		} else {
+			schedule, err := parseStartSchedule(&scheduledPatch, calendar)
+			if err != nil {
+				return err
+			}
+			// Report the scheduled time rather than the time applied ahead.
+			if t := schedule.Next(currentTime); !t.IsZero() {
+				scheduledPatchStatus.NextTime = &metav1.Time{Time: t.Add(getLeadTime(&scheduledPatch))}
 			}
 		}
+		if scheduledPatchStatus.NextTime != nil && (nextTime == nil || scheduledPatchStatus.NextTime.Before(nextTime)) {
+			nextPatchName = scheduledPatch.Name
+			nextTime = scheduledPatchStatus.NextTime
+		}
+		scheduledPatchStatuses = append(scheduledPatchStatuses, scheduledPatchStatus)
+	}
+	cronhpa.Status.ScheduledPatches = scheduledPatchStatuses
+	cronhpa.Status.NextPatchName = nextPatchName
+	cronhpa.Status.NextTime = nextTime
+	return nil
+}
+
+// GetCompletedPatchNames returns the names of the dated patches which have expired at the given time.
+func (cronhpa *CronHorizontalPodAutoscaler) GetCompletedPatchNames(currentTime time.Time) []string {
+	var completedPatchNames []string
+	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		scheduledPatch := scheduledPatch
+		if isCompletedPatch(&scheduledPatch, currentTime) {
+			completedPatchNames = append(completedPatchNames, scheduledPatch.Name)
+		}
+	}
+	return completedPatchNames
+}
+
+// UpdateCompletedPatches marks the expired dated patches completed in the status.
+func (cronhpa *CronHorizontalPodAutoscaler) UpdateCompletedPatches(ctx context.Context, currentTime time.Time, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	logger := log.FromContext(ctx)
 
+	completedPatchNames := cronhpa.GetCompletedPatchNames(currentTime)
+	if reflect.DeepEqual(cronhpa.Status.CompletedPatchNames, completedPatchNames) {
+		return nil
 	}
-	if cronhpa.Status.LastScheduledPatchName != currentPatchName {
-		logger.Info(fmt.Sprintf("Current patch changed from %s to %s", cronhpa.Status.LastScheduledPatchName, currentPatchName))
+	newPatchNames := make([]string, 0)
+	for _, patchName := range completedPatchNames {
+		if !containsString(cronhpa.Status.CompletedPatchNames, patchName) {
+			newPatchNames = append(newPatchNames, patchName)
+		}
 	}
-	return currentPatchName, nil
+	cronhpa.Status.CompletedPatchNames = completedPatchNames
+	if err := reconciler.Status().Update(ctx, cronhpa.ToCompatible()); err != nil {
+		return err
+	}
+	if len(newPatchNames) > 0 {
+		logger.Info(fmt.Sprintf("Completed %s", strings.Join(newPatchNames, ",")))
+		msg := fmt.Sprintf("Completed: %s", strings.Join(newPatchNames, ","))
+		reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeNormal, CronHPAEventCompleted, msg)
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
 
 func (cronhpa *CronHorizontalPodAutoscaler) CreateOrPatchHPA(ctx context.Context, patchName string, currentTime time.Time, reconciler *CronHorizontalPodAutoscalerReconciler) error {
@@ -202,68 +655,269 @@ func (cronhpa *CronHorizontalPodAutoscaler) CreateOrPatchHPA(ctx context.Context
 
 	logger.Info("Create or update HPA")
 
-	newhpa, err := cronhpa.NewHPA(patchName)
+	cronhpa.Status.SkipUntil = nil
+	if cronhpa.Spec.HPASelector != nil {
+		return cronhpa.PatchSelectedHPAs(ctx, patchName, currentTime, reconciler)
+	}
+	if cronhpa.Spec.ScaleTarget != nil {
+		return cronhpa.UpdateScale(ctx, patchName, currentTime, reconciler)
+	}
+	if cronhpa.Spec.ScaledObjectRef != nil {
+		return cronhpa.PatchScaledObject(ctx, patchName, currentTime, reconciler)
+	}
+
+	gv := reconciler.GetHPAGroupVersion()
+	obj, err := NewVersionedHPA(gv)
 	if err != nil {
 		return err
 	}
-	if err := controllerutil.SetControllerReference(cronhpa.ToCompatible(), newhpa, reconciler.Client.Scheme()); err != nil {
+	var hpa *autoscalingv2.HorizontalPodAutoscaler
+	skipped := false
+	if err := reconciler.Get(ctx, cronhpa.ToHPANamespacedName(), obj); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		if cronhpa.Spec.HPARef != nil {
+			return fmt.Errorf("No HPA %s to adopt", cronhpa.Spec.HPARef.Name)
+		}
+	} else {
+		skipped, err = cronhpa.IsSkipped(ctx, obj, currentTime, reconciler)
+		if err != nil {
+			return err
+		}
+		hpa, err = FromVersionedHPA(obj)
+		if err != nil {
+			return err
+		}
+	}
+
+	var newhpa *autoscalingv2.HorizontalPodAutoscaler
+	if cronhpa.Spec.HPARef != nil {
+		if cronhpa.UpdateBaselineHPASpec(hpa) {
+			logger.Info("Captured the baseline of the adopted HPA")
+		}
+		newhpa, err = cronhpa.NewAdoptedHPA(patchName, hpa)
+	} else {
+		newhpa, err = cronhpa.NewHPA(patchName)
+	}
+	if err != nil {
+		return err
+	}
+	if err := cronhpa.ApplyRamp(patchName, currentTime, hpa, newhpa); err != nil {
+		return err
+	}
+	var guarded *cronhpav1beta1.GuardedPatchStatus
+	if !skipped {
+		guarded = cronhpa.ApplyScaleDownGuard(patchName, currentTime, hpa, newhpa)
+	}
+	newobj, err := ToVersionedHPA(newhpa, gv)
+	if err != nil {
 		return err
 	}
+	// The adopted HPA is owned by others, e.g. Helm.
+	if cronhpa.Spec.HPARef == nil {
+		if err := controllerutil.SetControllerReference(cronhpa.ToCompatible(), newobj, reconciler.Client.Scheme()); err != nil {
+			return err
+		}
+	}
 
 	event := ""
 	msg := ""
-	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{}
-	if err := reconciler.Get(ctx, cronhpa.ToNamespacedName(), hpa); err != nil {
-		if !errors.IsNotFound(err) {
-			return err
-		}
-		if err := reconciler.Create(ctx, newhpa); err != nil {
+	syncedStatus, syncedReason := metav1.ConditionTrue, ""
+	suspended := false
+	deferred := false
+	if hpa == nil {
+		if err := reconciler.Create(ctx, newobj); err != nil {
 			return err
 		}
 		logger.Info("Created an HPA successfully")
 		event = CronHPAEventCreated
 		msg = "Created HPA"
+		syncedReason = conditionReasonCreated
 	} else {
-		if hpa.Annotations[annotationNameSkip] == "true" {
+		if skipped {
 			logger.Info("Skip updating an HPA by an annotation")
 			event = CronHPAEventSkipped
 			msg = "Skipped updating HPA by an annotation"
+			syncedStatus, syncedReason = metav1.ConditionFalse, conditionReasonSkipped
+			suspended = true
+		} else if guarded != nil && cronhpa.getScaleDownGuardPolicy() == cronhpav1beta1.ScaleDownGuardPolicyDefer {
+			logger.Info(fmt.Sprintf("Defer updating an HPA because %s", guarded.Reason))
+			event = CronHPAEventScaleDownGuarded
+			msg = fmt.Sprintf("Deferred updating HPA with %s (%s)", patchName, guarded.Reason)
+			syncedStatus, syncedReason = metav1.ConditionFalse, conditionReasonScaleDownDeferred
+			deferred = true
 		} else if reflect.DeepEqual(hpa.Spec, newhpa.Spec) {
 			logger.Info("Skip updating an HPA with no changes")
 			event = CronHPAEventSkipped
 			msg = "Skipped updating HPA with no changes"
+			syncedReason = conditionReasonNoChanges
 		} else {
-			patch := client.MergeFrom(hpa)
-			if err := reconciler.Patch(ctx, newhpa, patch); err != nil {
+			patch := client.MergeFrom(obj)
+			if err := reconciler.Patch(ctx, newobj, patch); err != nil {
 				return err
 			}
 			logger.Info("Updated an HPA successfully")
 			event = CronHPAEventUpdated
 			msg = "Updated HPA"
+			syncedReason = conditionReasonUpdated
+		}
+		if guarded != nil && cronhpa.getScaleDownGuardPolicy() == cronhpav1beta1.ScaleDownGuardPolicyClamp {
+			event = CronHPAEventScaleDownGuarded
+			msg = fmt.Sprintf("%s clamped (%s)", msg, guarded.Reason)
+			syncedStatus, syncedReason = metav1.ConditionFalse, conditionReasonScaleDownClamped
 		}
 	}
 
+	// The deferred patch is not recorded as the last scheduled patch because the HPA is unchanged,
+	// so that it's found again on retry and applied as a new patch, e.g. with its ramp.
+	if deferred {
+		return cronhpa.saveSyncStatus(ctx, event, msg, syncedStatus, syncedReason, suspended, reconciler)
+	}
 	if event != "" {
-		cronhpa.Status.LastCronTimestamp = &metav1.Time{
-			Time: currentTime,
+		return cronhpa.updateSyncStatus(ctx, patchName, currentTime, event, msg, syncedStatus, syncedReason, suspended, reconciler)
+	}
+
+	return nil
+}
+
+// updateSyncStatus records the patch applied at the given time and the sync result in the status and an event.
+func (cronhpa *CronHorizontalPodAutoscaler) updateSyncStatus(ctx context.Context, patchName string, currentTime time.Time, event CronHPAEvent, msg string, syncedStatus metav1.ConditionStatus, syncedReason string, suspended bool, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	if patchName != "" {
+		msg = fmt.Sprintf("%s with %s", msg, patchName)
+		scheduledPatch := cronhpa.getScheduledPatch(patchName)
+		triggeredPatch := cronhpa.Status.TriggeredPatch
+		if scheduledPatch != nil && (triggeredPatch == nil || triggeredPatch.Name != patchName) {
+			scheduledTime, err := getLeadingScheduleTime(scheduledPatch, currentTime)
+			if err != nil {
+				return err
+			}
+			if !scheduledTime.IsZero() {
+				msg = fmt.Sprintf("%s ahead of the schedule at %s", msg, scheduledTime.Format(time.RFC3339))
+			}
 		}
-		cronhpa.Status.LastScheduledPatchName = patchName
-		if err := reconciler.Status().Update(ctx, cronhpa.ToCompatible()); err != nil {
-			return err
+	}
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{
+		Time: currentTime,
+	}
+	cronhpa.Status.LastScheduledPatchName = patchName
+	return cronhpa.saveSyncStatus(ctx, event, msg, syncedStatus, syncedReason, suspended, reconciler)
+}
+
+// saveSyncStatus records the sync result in the conditions, saves the status and emits an event.
+func (cronhpa *CronHorizontalPodAutoscaler) saveSyncStatus(ctx context.Context, event CronHPAEvent, msg string, syncedStatus metav1.ConditionStatus, syncedReason string, suspended bool, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	cronhpa.SetCondition(cronhpav1beta1.ConditionTypeHPASynced, syncedStatus, syncedReason, msg)
+	if suspended {
+		suspendedMsg := "Updating HPA is skipped by an annotation"
+		if cronhpa.Status.SkipUntil != nil {
+			suspendedMsg = fmt.Sprintf("%s until %s", suspendedMsg, cronhpa.Status.SkipUntil.Format(time.RFC3339))
 		}
-		if patchName != "" {
-			msg = fmt.Sprintf("%s with %s", msg, patchName)
+		cronhpa.SetCondition(cronhpav1beta1.ConditionTypeSuspended, metav1.ConditionTrue, conditionReasonSkipAnnotation, suspendedMsg)
+	} else {
+		cronhpa.SetCondition(cronhpav1beta1.ConditionTypeSuspended, metav1.ConditionFalse, conditionReasonNotSuspended, "Updating HPA is not suspended")
+	}
+	if err := reconciler.Status().Update(ctx, cronhpa.ToCompatible()); err != nil {
+		return err
+	}
+	reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeNormal, event, msg)
+	return nil
+}
+
+// IsSuspended returns true if applying the scheduled patches is suspended by the spec.
+func (cronhpa *CronHorizontalPodAutoscaler) IsSuspended() bool {
+	return cronhpa.Spec.Suspend != nil && *cronhpa.Spec.Suspend
+}
+
+// UpdateSuspendedStatus marks the CronHPA suspended by the spec and updates the status.
+// The last scheduled patch and the last cron timestamp are kept so that the patch which should be active is applied on resume.
+func (cronhpa *CronHorizontalPodAutoscaler) UpdateSuspendedStatus(ctx context.Context, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	logger := log.FromContext(ctx)
+
+	logger.Info("Skip applying patches suspended by the spec")
+	condition := meta.FindStatusCondition(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeSuspended)
+	transited := condition == nil || condition.Reason != conditionReasonSuspended
+	cronhpa.SetCondition(cronhpav1beta1.ConditionTypeSuspended, metav1.ConditionTrue, conditionReasonSuspended, "Applying the scheduled patches is suspended by the spec")
+	if err := reconciler.Status().Update(ctx, cronhpa.ToCompatible()); err != nil {
+		return err
+	}
+	if transited {
+		reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeNormal, CronHPAEventSuspended, "Suspended applying the scheduled patches")
+	}
+	return nil
+}
+
+// SetCondition sets the condition observed at the current generation.
+func (cronhpa *CronHorizontalPodAutoscaler) SetCondition(conditionType string, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cronhpa.Status.Conditions, metav1.Condition{
+		Type:               conditionType,
+		Status:             status,
+		ObservedGeneration: cronhpa.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	cronhpa.Status.ObservedGeneration = cronhpa.Generation
+}
+
+// UpdateConditionWithError sets the condition false with the error and updates the status.
+// It returns the given error so that callers can return it as is.
+func (cronhpa *CronHorizontalPodAutoscaler) UpdateConditionWithError(ctx context.Context, reconciler *CronHorizontalPodAutoscalerReconciler, conditionType, reason string, err error) error {
+	logger := log.FromContext(ctx)
+
+	cronhpa.SetCondition(conditionType, metav1.ConditionFalse, reason, err.Error())
+	if uerr := reconciler.Status().Update(ctx, cronhpa.ToCompatible()); uerr != nil {
+		logger.Error(uerr, "Failed to update the conditions")
+	}
+	return err
+}
+
+// SetDefaultTimezones fills in the effective timezones of the scheduled patches without their own timezones,
+// which are the timezone of the spec, or the given default timezone.
+func (cronhpa *CronHorizontalPodAutoscaler) SetDefaultTimezones(defaultTimezone string) {
+	timezone := cronhpa.Spec.Timezone
+	if timezone == "" {
+		timezone = defaultTimezone
+	}
+	for i := range cronhpa.Spec.ScheduledPatches {
+		scheduledPatch := &cronhpa.Spec.ScheduledPatches[i]
+		if scheduledPatch.Timezone == "" && !isDatedPatch(scheduledPatch) {
+			scheduledPatch.Timezone = timezone
 		}
-		reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeNormal, event, msg)
 	}
+}
 
+// ValidateSchedules validates the schedules and the timezones of the scheduled patches.
+func (cronhpa *CronHorizontalPodAutoscaler) ValidateSchedules() error {
+	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		if _, err := loadLocation(scheduledPatch.Timezone); err != nil {
+			return fmt.Errorf("Invalid timezone of patch %s: %w", scheduledPatch.Name, err)
+		}
+		if isDatedPatch(&scheduledPatch) {
+			continue
+		}
+		if _, err := parseSchedule(scheduledPatch.Schedule, scheduledPatch.Timezone); err != nil {
+			return fmt.Errorf("Invalid schedule of patch %s: %w", scheduledPatch.Name, err)
+		}
+		if scheduledPatch.EndSchedule != "" {
+			if _, err := parseSchedule(scheduledPatch.EndSchedule, scheduledPatch.Timezone); err != nil {
+				return fmt.Errorf("Invalid end schedule of patch %s: %w", scheduledPatch.Name, err)
+			}
+		}
+	}
 	return nil
 }
 
-func (cronhpa *CronHorizontalPodAutoscaler) ToCompatible() *v1alpha1.CronHorizontalPodAutoscaler {
-	return (*v1alpha1.CronHorizontalPodAutoscaler)(cronhpa)
+func (cronhpa *CronHorizontalPodAutoscaler) ToCompatible() *cronhpav1beta1.CronHorizontalPodAutoscaler {
+	return (*cronhpav1beta1.CronHorizontalPodAutoscaler)(cronhpa)
 }
 
 func (cronhpa *CronHorizontalPodAutoscaler) ToNamespacedName() types.NamespacedName {
 	return types.NamespacedName{Namespace: cronhpa.Namespace, Name: cronhpa.Name}
 }
+
+// ToHPANamespacedName returns the namespaced name of the adopted HPA, or the generated HPA named after the CronHPA.
+func (cronhpa *CronHorizontalPodAutoscaler) ToHPANamespacedName() types.NamespacedName {
+	if cronhpa.Spec.HPARef != nil {
+		return types.NamespacedName{Namespace: cronhpa.Namespace, Name: cronhpa.Spec.HPARef.Name}
+	}
+	return cronhpa.ToNamespacedName()
+}
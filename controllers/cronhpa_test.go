@@ -22,19 +22,22 @@ import (
 	"testing"
 	"time"
 
-	cronhpav1alpha1 "github.com/dtaniwaki/cron-hpa/api/v1alpha1"
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
 	"github.com/dtaniwaki/cron-hpa/test"
 	"github.com/stretchr/testify/assert"
-	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/yaml"
 )
 
 func TestNewHPA(t *testing.T) {
 	cronHPAManifest := `
-apiVersion: cron-hpa.dtaniwaki.github.com/v1alpha1
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
 kind: CronHorizontalPodAutoscaler
 metadata:
   name: cron-hpa-sample
@@ -78,7 +81,7 @@ spec:
 	}
 
 	defaultHPAManifest := `
-apiVersion: autoscaling/v2beta2
+apiVersion: autoscaling/v2
 kind: HorizontalPodAutoscaler
 metadata:
   name: cron-hpa-sample
@@ -99,7 +102,7 @@ spec:
         averageUtilization: 50
 `
 
-	defaultHPA := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+	defaultHPA := &autoscalingv2.HorizontalPodAutoscaler{}
 	err = yaml.Unmarshal([]byte(defaultHPAManifest), defaultHPA)
 	if !assert.NoError(t, err) {
 		t.FailNow()
@@ -116,7 +119,7 @@ spec:
 	}
 
 	withPatchHPAManifest := `
-apiVersion: autoscaling/v2beta2
+apiVersion: autoscaling/v2
 kind: HorizontalPodAutoscaler
 metadata:
   name: cron-hpa-sample
@@ -137,7 +140,7 @@ spec:
         averageUtilization: 30
 `
 
-	withPatchHPA := &autoscalingv2beta2.HorizontalPodAutoscaler{}
+	withPatchHPA := &autoscalingv2.HorizontalPodAutoscaler{}
 	err = yaml.Unmarshal([]byte(withPatchHPAManifest), withPatchHPA)
 	if !assert.NoError(t, err) {
 		t.FailNow()
@@ -152,11 +155,632 @@ spec:
 	}
 }
 
+func TestNewHPAWithBehavior(t *testing.T) {
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+      behavior:
+        scaleUp:
+          stabilizationWindowSeconds: 0
+          selectPolicy: Max
+          policies:
+          - type: Percent
+            value: 100
+            periodSeconds: 15
+          - type: Pods
+            value: 4
+            periodSeconds: 15
+  scheduledPatches:
+  - name: morning
+    schedule: "0 7 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      behavior:
+        scaleUp:
+          policies:
+          - type: Pods
+            value: 10
+            periodSeconds: 15
+        scaleDown:
+          stabilizationWindowSeconds: 600
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      behavior:
+        scaleUp:
+          selectPolicy: Min
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	morningBehaviorManifest := `
+scaleUp:
+  stabilizationWindowSeconds: 0
+  selectPolicy: Max
+  policies:
+  - type: Percent
+    value: 100
+    periodSeconds: 15
+  - type: Pods
+    value: 10
+    periodSeconds: 15
+scaleDown:
+  stabilizationWindowSeconds: 600
+`
+
+	morningBehavior := &autoscalingv2.HorizontalPodAutoscalerBehavior{}
+	err = yaml.Unmarshal([]byte(morningBehaviorManifest), morningBehavior)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	hpa, err := cronhpa.NewHPA("morning")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, morningBehavior, hpa.Spec.Behavior) {
+		t.FailNow()
+	}
+
+	nighttimeBehaviorManifest := `
+scaleUp:
+  stabilizationWindowSeconds: 0
+  selectPolicy: Min
+  policies:
+  - type: Percent
+    value: 100
+    periodSeconds: 15
+  - type: Pods
+    value: 4
+    periodSeconds: 15
+`
+
+	nighttimeBehavior := &autoscalingv2.HorizontalPodAutoscalerBehavior{}
+	err = yaml.Unmarshal([]byte(nighttimeBehaviorManifest), nighttimeBehavior)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	hpa, err = cronhpa.NewHPA("nighttime")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, nighttimeBehavior, hpa.Spec.Behavior) {
+		t.FailNow()
+	}
+
+	// The template is not modified.
+	if !assert.Equal(t, autoscalingv2.ScalingPolicySelect("Max"), *cronhpa.Spec.Template.Spec.Behavior.ScaleUp.SelectPolicy) {
+		t.FailNow()
+	}
+}
+
+func TestNewHPAWithGenericPatches(t *testing.T) {
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    metadata:
+      labels:
+        app: nginx
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+      metrics:
+      - type: Resource
+        resource:
+          name: cpu
+          target:
+            type: Utilization
+            averageUtilization: 50
+  scheduledPatches:
+  - name: one
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 3
+    strategicMergePatch:
+      metadata:
+        labels:
+          schedule: one
+      spec:
+        maxReplicas: 15
+    jsonPatch:
+    - op: replace
+      path: /spec/metrics/0/resource/target/averageUtilization
+      value: 30
+    - op: add
+      path: /spec/metrics/-
+      value:
+        type: Resource
+        resource:
+          name: memory
+          target:
+            type: Utilization
+            averageUtilization: 80
+  - name: invalid-smp
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    strategicMergePatch:
+      metadata:
+        name: foo
+  - name: invalid-json
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    jsonPatch:
+    - op: replace
+      path: /status/currentReplicas
+      value: 3
+  - name: missing-path
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    jsonPatch:
+    - op: replace
+      path: /spec/behavior/scaleUp
+      value: {}
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	withPatchHPAManifest := `
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+  labels:
+    app: nginx
+    schedule: one
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: cron-hpa-nginx
+  minReplicas: 3
+  maxReplicas: 15
+  metrics:
+  - type: Resource
+    resource:
+      name: cpu
+      target:
+        type: Utilization
+        averageUtilization: 30
+  - type: Resource
+    resource:
+      name: memory
+      target:
+        type: Utilization
+        averageUtilization: 80
+`
+
+	withPatchHPA := &autoscalingv2.HorizontalPodAutoscaler{}
+	err = yaml.Unmarshal([]byte(withPatchHPAManifest), withPatchHPA)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	hpa, err := cronhpa.NewHPA("one")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, withPatchHPA, hpa) {
+		t.FailNow()
+	}
+
+	// Patches out of the spec and the metadata.
+	_, err = cronhpa.NewHPA("invalid-smp")
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+	_, err = cronhpa.NewHPA("invalid-json")
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+
+	// Patches failed to apply.
+	_, err = cronhpa.NewHPA("missing-path")
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+}
+
+func TestNewAdoptedHPA(t *testing.T) {
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  hpaRef:
+    name: nginx
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 5
+    strategicMergePatch:
+      metadata:
+        labels:
+          schedule: daytime
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, types.NamespacedName{Namespace: "default", Name: "nginx"}, cronhpa.ToHPANamespacedName()) {
+		t.FailNow()
+	}
+
+	hpaManifest := `
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: nginx
+  namespace: default
+  labels:
+    app.kubernetes.io/managed-by: Helm
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: nginx
+  maxReplicas: 10
+`
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	err = yaml.Unmarshal([]byte(hpaManifest), hpa)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// No baseline.
+	_, err = cronhpa.NewAdoptedHPA("daytime", hpa)
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+
+	// Capture the baseline.
+	if !assert.True(t, cronhpa.UpdateBaselineHPASpec(hpa)) {
+		t.FailNow()
+	}
+	newhpa, err := cronhpa.NewAdoptedHPA("daytime", hpa)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(5), *newhpa.Spec.MinReplicas) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(10), newhpa.Spec.MaxReplicas) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, map[string]string{"app.kubernetes.io/managed-by": "Helm", "schedule": "daytime"}, newhpa.Labels) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, hpa.Spec.MinReplicas) {
+		t.FailNow()
+	}
+
+	// Keep the baseline while the patch is applied.
+	cronhpa.Status.LastScheduledPatchName = "daytime"
+	if !assert.False(t, cronhpa.UpdateBaselineHPASpec(newhpa)) {
+		t.FailNow()
+	}
+	newhpa, err = cronhpa.NewAdoptedHPA("", newhpa)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, hpa.Spec, newhpa.Spec) {
+		t.FailNow()
+	}
+
+	// Update the baseline changed while no patch is applied.
+	cronhpa.Status.LastScheduledPatchName = ""
+	if !assert.False(t, cronhpa.UpdateBaselineHPASpec(hpa)) {
+		t.FailNow()
+	}
+	hpa.Spec.MaxReplicas = 20
+	if !assert.True(t, cronhpa.UpdateBaselineHPASpec(hpa)) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(20), cronhpa.Status.BaselineHPASpec.MaxReplicas) {
+		t.FailNow()
+	}
+
+	// No template to generate an HPA.
+	_, err = cronhpa.NewHPA("")
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+}
+
 func TestGetCurrentPatchName(t *testing.T) {
 	ctx := context.TODO()
 
 	cronHPAManifest := `
-apiVersion: cron-hpa.dtaniwaki.github.com/v1alpha1
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+      metrics:
+      - type: Resource
+        resource:
+          name: cpu
+          target:
+            type: Utilization
+            averageUtilization: 50
+  scheduledPatches:
+  - name: weekday
+    schedule: "0 0 * 10 mon-fri"
+    timezone: "Asia/Tokyo"
+  - name: weekend
+    schedule: "0 0 * 10 sat,sun"
+    timezone: "Asia/Tokyo"
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T00:00:00+09:00"))
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{
+		Time: currentTime,
+	}
+
+	// In-range weekday.
+	_ = currentTime.UnmarshalText([]byte("2021-10-04T00:00:00+09:00")) // Mon
+	patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "weekday", patchName) {
+		t.FailNow()
+	}
+
+	// In-range weekend.
+	_ = currentTime.UnmarshalText([]byte("2021-10-02T00:00:00+09:00")) // Sat
+	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "weekend", patchName) {
+		t.FailNow()
+	}
+
+	// Out-range date
+	_ = currentTime.UnmarshalText([]byte("2021-09-15T00:00:00+09:00")) // Wed
+	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "", patchName) {
+		t.FailNow()
+	}
+
+	// Out-range date with last patch name.
+	_ = currentTime.UnmarshalText([]byte("2021-09-15T00:00:00+09:00")) // Wed
+	cronhpa.Status.LastScheduledPatchName = "weekday"
+	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "weekday", patchName) {
+		t.FailNow()
+	}
+
+	// Lost scheduled patch
+	cronhpa.Status.LastScheduledPatchName = "weekday"
+	cronhpa.Spec.ScheduledPatches = []cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch{}
+	_ = currentTime.UnmarshalText([]byte("2021-10-02T00:00:00+09:00")) // Sat
+	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "", patchName) {
+		t.FailNow()
+	}
+
+	// Without last timestamp
+	cronhpa.Status.LastScheduledPatchName = "weekday"
+	cronhpa.Status.LastCronTimestamp = nil
+	_ = currentTime.UnmarshalText([]byte("2021-10-02T00:00:00+09:00")) // Sat
+	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "", patchName) {
+		t.FailNow()
+	}
+}
+
+func TestGetCurrentPatchNameWithWindows(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: business
+    schedule: "0 9 * * mon-fri"
+    endSchedule: "0 18 * * mon-fri"
+    timezone: "Asia/Tokyo"
+  - name: lunch
+    schedule: "0 12 * * *"
+    duration: 1h
+    timezone: "Asia/Tokyo"
+  - name: night
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-10-04T08:00:00+09:00")) // Mon
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{
+		Time: currentTime,
+	}
+
+	for _, c := range []struct {
+		currentTime string
+		patchName   string
+	}{
+		{"2021-10-04T10:00:00+09:00", "business"},
+		{"2021-10-04T12:30:00+09:00", "lunch"},
+		{"2021-10-04T13:00:00+09:00", "business"},
+		{"2021-10-04T19:00:00+09:00", ""},
+		{"2021-10-04T23:00:00+09:00", "night"},
+	} {
+		_ = currentTime.UnmarshalText([]byte(c.currentTime))
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+	}
+
+	// Closed window with last patch name.
+	_ = currentTime.UnmarshalText([]byte("2021-10-04T12:00:00+09:00")) // Mon
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{
+		Time: currentTime,
+	}
+	cronhpa.Status.LastScheduledPatchName = "lunch"
+	_ = currentTime.UnmarshalText([]byte("2021-10-04T13:30:00+09:00"))
+	patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "business", patchName) {
+		t.FailNow()
+	}
+
+	// Window started after the last point patch.
+	_ = currentTime.UnmarshalText([]byte("2021-10-04T22:00:00+09:00")) // Mon
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{
+		Time: currentTime,
+	}
+	cronhpa.Status.LastScheduledPatchName = "night"
+	_ = currentTime.UnmarshalText([]byte("2021-10-05T10:00:00+09:00")) // Tue
+	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "business", patchName) {
+		t.FailNow()
+	}
+
+	// Without last timestamp
+	cronhpa.Status.LastScheduledPatchName = ""
+	cronhpa.Status.LastCronTimestamp = nil
+	_ = currentTime.UnmarshalText([]byte("2021-10-09T12:30:00+09:00")) // Sat
+	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "lunch", patchName) {
+		t.FailNow()
+	}
+	_ = currentTime.UnmarshalText([]byte("2021-10-09T13:30:00+09:00")) // Sat
+	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "", patchName) {
+		t.FailNow()
+	}
+
+	// Next reconcile time.
+	for _, c := range []struct {
+		currentTime string
+		nextTime    string
+	}{
+		{"2021-10-04T10:00:00+09:00", "2021-10-04T18:00:00+09:00"},
+		{"2021-10-04T12:30:00+09:00", "2021-10-04T13:00:00+09:00"},
+		{"2021-10-04T19:00:00+09:00", ""},
+	} {
+		_ = currentTime.UnmarshalText([]byte(c.currentTime))
+		nextTime, err := cronhpa.GetNextReconcileTime(currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		expected := time.Time{}
+		if c.nextTime != "" {
+			_ = expected.UnmarshalText([]byte(c.nextTime))
+		}
+		if !assert.True(t, expected.Equal(nextTime), c.currentTime) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestGetCurrentPatchNameWithDatedPatches(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
 kind: CronHorizontalPodAutoscaler
 metadata:
   name: cron-hpa-sample
@@ -170,20 +794,16 @@ spec:
         name: cron-hpa-nginx
       minReplicas: 1
       maxReplicas: 10
-      metrics:
-      - type: Resource
-        resource:
-          name: cpu
-          target:
-            type: Utilization
-            averageUtilization: 50
   scheduledPatches:
-  - name: weekday
-    schedule: "0 0 * 10 mon-fri"
-    timezone: "Asia/Tokyo"
-  - name: weekend
-    schedule: "0 0 * 10 sat,sun"
+  - name: nighttime
+    schedule: "0 22 * * *"
     timezone: "Asia/Tokyo"
+  - name: campaign
+    startTime: "2026-11-27T08:00:00+09:00"
+    endTime: "2026-11-28T02:00:00+09:00"
+status:
+  lastCronTimestamp: "2026-11-26T22:00:00+09:00"
+  lastScheduledPatchName: nighttime
 `
 
 	cronhpa := &CronHorizontalPodAutoscaler{}
@@ -192,83 +812,400 @@ spec:
 		t.FailNow()
 	}
 
+	for _, c := range []struct {
+		currentTime         string
+		patchName           string
+		nextTime            string
+		completedPatchNames []string
+	}{
+		{"2026-11-27T07:00:00+09:00", "nighttime", "2026-11-27T08:00:00+09:00", nil},
+		{"2026-11-27T10:00:00+09:00", "campaign", "2026-11-28T02:00:00+09:00", nil},
+		{"2026-11-28T03:00:00+09:00", "nighttime", "", []string{"campaign"}},
+	} {
+		currentTime := time.Time{}
+		_ = currentTime.UnmarshalText([]byte(c.currentTime))
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+		nextTime, err := cronhpa.GetNextReconcileTime(currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		expected := time.Time{}
+		if c.nextTime != "" {
+			_ = expected.UnmarshalText([]byte(c.nextTime))
+		}
+		if !assert.True(t, expected.Equal(nextTime), c.currentTime) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.completedPatchNames, cronhpa.GetCompletedPatchNames(currentTime), c.currentTime) {
+			t.FailNow()
+		}
+	}
+
+	// Revert to the template after the dated patch expires.
 	currentTime := time.Time{}
-	_ = currentTime.UnmarshalText([]byte("2021-09-04T00:00:00+09:00"))
+	_ = currentTime.UnmarshalText([]byte("2026-11-27T10:00:00+09:00"))
 	cronhpa.Status.LastCronTimestamp = &metav1.Time{
 		Time: currentTime,
 	}
+	cronhpa.Status.LastScheduledPatchName = "campaign"
+	_ = currentTime.UnmarshalText([]byte("2026-11-28T02:00:00+09:00"))
+	cronhpa.Spec.ScheduledPatches = cronhpa.Spec.ScheduledPatches[1:]
+	patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "", patchName) {
+		t.FailNow()
+	}
+}
 
-	// In-range weekday.
-	_ = currentTime.UnmarshalText([]byte("2021-10-04T00:00:00+09:00")) // Mon
-	patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime)
+func TestGetCurrentPatchNameWithPriority(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: hourly
+    schedule: "0 * * * *"
+    timezone: "Asia/Tokyo"
+  - name: maintenance
+    schedule: "30 2 * * sun"
+    duration: 3h
+    timezone: "Asia/Tokyo"
+    priority: 10
+  - name: lunch
+    schedule: "0 12 * * *"
+    duration: 1h
+    timezone: "Asia/Tokyo"
+    priority: 10
+status:
+  lastCronTimestamp: "2021-10-03T02:00:00+09:00"
+  lastScheduledPatchName: hourly
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	if !assert.Equal(t, "weekday", patchName) {
+
+	for _, c := range []struct {
+		currentTime string
+		patchName   string
+	}{
+		{"2021-10-03T02:15:00+09:00", "hourly"},
+		{"2021-10-03T04:15:00+09:00", "maintenance"}, // Sun
+		{"2021-10-03T05:45:00+09:00", "hourly"},
+		{"2021-10-03T12:15:00+09:00", "lunch"},
+	} {
+		currentTime := time.Time{}
+		_ = currentTime.UnmarshalText([]byte(c.currentTime))
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+	}
+
+	// Fired patches.
+	cronhpa.Status.LastScheduledPatchName = "maintenance"
+	for _, c := range []struct {
+		currentTime string
+		firedName   string
+		patchName   string
+	}{
+		{"2021-10-03T03:00:00+09:00", "hourly", "maintenance"},
+		{"2021-10-03T06:00:00+09:00", "hourly", "hourly"},
+		{"2021-10-04T02:30:00+09:00", "maintenance", "maintenance"},
+	} {
+		currentTime := time.Time{}
+		_ = currentTime.UnmarshalText([]byte(c.currentTime))
+		patchName, err := cronhpa.GetFiredPatchName(ctx, c.firedName, currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+	}
+
+	// Point patch with a higher priority stays until a patch with the same or a higher priority fires.
+	cronhpa.Spec.ScheduledPatches[1].Duration = nil
+	cronhpa.Status.LastCronTimestamp.Time = cronhpa.Status.LastCronTimestamp.Add(30 * time.Minute)
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-10-03T11:15:00+09:00"))
+	patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "maintenance", patchName) {
 		t.FailNow()
 	}
 
-	// In-range weekend.
-	_ = currentTime.UnmarshalText([]byte("2021-10-02T00:00:00+09:00")) // Sat
-	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime)
+	// Point patch fired in a window with a higher priority is applied after the window closes.
+	cronhpa.Spec.ScheduledPatches = []cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch{
+		{Name: "afternoon", Schedule: "30 12 * * *", Timezone: "Asia/Tokyo"},
+		cronhpa.Spec.ScheduledPatches[2],
+	}
+	cronhpa.Status.LastScheduledPatchName = "lunch"
+	_ = cronhpa.Status.LastCronTimestamp.UnmarshalText([]byte("2021-10-04T12:00:00+09:00"))
+	_ = currentTime.UnmarshalText([]byte("2021-10-04T12:30:00+09:00"))
+	patchName, err = cronhpa.GetFiredPatchName(ctx, "afternoon", currentTime, nil)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	if !assert.Equal(t, "weekend", patchName) {
+	if !assert.Equal(t, "lunch", patchName) {
 		t.FailNow()
 	}
+	cronhpa.Status.LastCronTimestamp.Time = currentTime
+	for _, c := range []struct {
+		currentTime string
+		patchName   string
+	}{
+		{"2021-10-04T12:45:00+09:00", "lunch"},
+		{"2021-10-04T13:00:01+09:00", "afternoon"},
+	} {
+		_ = currentTime.UnmarshalText([]byte(c.currentTime))
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+	}
+}
 
-	// Out-range date
-	_ = currentTime.UnmarshalText([]byte("2021-09-15T00:00:00+09:00")) // Wed
-	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime)
+func TestGetCurrentPatchNameWithLeadTime(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    leadTime: 15m
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+  - name: lunch
+    schedule: "0 12 * * *"
+    duration: 1h
+    timezone: "Asia/Tokyo"
+    leadTime: 10m
+  - name: midnight
+    schedule: "0 0 * * *"
+    timezone: "Asia/Tokyo"
+    leadTime: 30m
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	if !assert.Equal(t, "", patchName) {
+
+	parseTime := func(s string) time.Time {
+		t := time.Time{}
+		_ = t.UnmarshalText([]byte(s))
+		return t
+	}
+
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{Time: parseTime("2021-10-04T06:00:00+09:00")}
+	cronhpa.Status.LastScheduledPatchName = "nighttime"
+	for _, c := range []struct {
+		currentTime string
+		patchName   string
+	}{
+		{"2021-10-04T07:40:00+09:00", "nighttime"},
+		{"2021-10-04T07:45:00+09:00", "daytime"},
+		{"2021-10-04T11:50:00+09:00", "lunch"},
+		{"2021-10-04T12:50:00+09:00", "lunch"},
+		{"2021-10-04T13:00:00+09:00", "daytime"},
+	} {
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, parseTime(c.currentTime), nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+	}
+
+	// The window closes at the scheduled end.
+	nextTime, err := cronhpa.GetNextReconcileTime(parseTime("2021-10-04T11:55:00+09:00"), nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, parseTime("2021-10-04T13:00:00+09:00").Equal(nextTime), nextTime) {
 		t.FailNow()
 	}
 
-	// Out-range date with last patch name.
-	_ = currentTime.UnmarshalText([]byte("2021-09-15T00:00:00+09:00")) // Wed
-	cronhpa.Status.LastScheduledPatchName = "weekday"
-	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime)
+	// The status reports the scheduled time.
+	err = cronhpa.SetNextSchedules(parseTime("2021-10-04T07:00:00+09:00"), nil)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	if !assert.Equal(t, "weekday", patchName) {
+	if !assert.Equal(t, "daytime", cronhpa.Status.NextPatchName) {
+		t.FailNow()
+	}
+	if !assert.True(t, parseTime("2021-10-04T08:00:00+09:00").Equal(cronhpa.Status.NextTime.Time)) {
+		t.FailNow()
+	}
+	scheduledTime, err := getLeadingScheduleTime(&cronhpa.Spec.ScheduledPatches[0], parseTime("2021-10-04T07:45:00+09:00"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, parseTime("2021-10-04T08:00:00+09:00").Equal(scheduledTime)) {
+		t.FailNow()
+	}
+	scheduledTime, err = getLeadingScheduleTime(&cronhpa.Spec.ScheduledPatches[0], parseTime("2021-10-04T08:00:00+09:00"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, scheduledTime.IsZero()) {
 		t.FailNow()
 	}
 
-	// Lost scheduled patch
-	cronhpa.Status.LastScheduledPatchName = "weekday"
-	cronhpa.Spec.ScheduledPatches = []cronhpav1alpha1.CronHorizontalPodAutoscalerScheduledPatch{}
-	_ = currentTime.UnmarshalText([]byte("2021-10-02T00:00:00+09:00")) // Sat
-	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime)
+	// The excluded dates are of the scheduled time.
+	excluded, err := cronhpa.IsExcluded("midnight", parseTime("2021-10-04T23:30:00+09:00"), ExclusionCalendar{"2021-10-05": true})
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	if !assert.Equal(t, "", patchName) {
+	if !assert.True(t, excluded) {
 		t.FailNow()
 	}
+}
 
-	// Without last timestamp
-	cronhpa.Status.LastScheduledPatchName = "weekday"
-	cronhpa.Status.LastCronTimestamp = nil
-	_ = currentTime.UnmarshalText([]byte("2021-10-02T00:00:00+09:00")) // Sat
-	patchName, err = cronhpa.GetCurrentPatchName(ctx, currentTime)
+func TestSetNextSchedules(t *testing.T) {
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+  - name: campaign
+    startTime: "2021-10-05T08:00:00+09:00"
+    endTime: "2021-10-06T02:00:00+09:00"
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	if !assert.Equal(t, "", patchName) {
+
+	parseTime := func(s string) time.Time {
+		t := time.Time{}
+		_ = t.UnmarshalText([]byte(s))
+		return t
+	}
+
+	err = cronhpa.SetNextSchedules(parseTime("2021-10-04T10:00:00+09:00"), nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Len(t, cronhpa.Status.ScheduledPatches, 3) {
+		t.FailNow()
+	}
+	for i, nextTime := range []string{"2021-10-05T08:00:00+09:00", "2021-10-04T22:00:00+09:00", "2021-10-05T08:00:00+09:00"} {
+		if !assert.True(t, parseTime(nextTime).Equal(cronhpa.Status.ScheduledPatches[i].NextTime.Time), nextTime) {
+			t.FailNow()
+		}
+	}
+	if !assert.Equal(t, "nighttime", cronhpa.Status.NextPatchName) {
+		t.FailNow()
+	}
+	if !assert.True(t, parseTime("2021-10-04T22:00:00+09:00").Equal(cronhpa.Status.NextTime.Time)) {
+		t.FailNow()
+	}
+
+	// Skip the excluded dates and the started dated patch.
+	err = cronhpa.SetNextSchedules(parseTime("2021-10-05T23:00:00+09:00"), ExclusionCalendar{"2021-10-06": true})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, parseTime("2021-10-07T08:00:00+09:00").Equal(cronhpa.Status.ScheduledPatches[0].NextTime.Time)) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, cronhpa.Status.ScheduledPatches[2].NextTime) {
+		t.FailNow()
+	}
+	if !assert.True(t, parseTime("2021-10-07T22:00:00+09:00").Equal(cronhpa.Status.ScheduledPatches[1].NextTime.Time)) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "daytime", cronhpa.Status.NextPatchName) {
+		t.FailNow()
+	}
+	if !assert.True(t, parseTime("2021-10-07T08:00:00+09:00").Equal(cronhpa.Status.NextTime.Time)) {
 		t.FailNow()
 	}
 }
 
 func TestCreateOrPatchHPA(t *testing.T) {
+	for _, gv := range HPAGroupVersions {
+		t.Run(gv.String(), func(t *testing.T) {
+			testCreateOrPatchHPA(t, gv)
+		})
+	}
+}
+
+func testCreateOrPatchHPA(t *testing.T, gv schema.GroupVersion) {
 	ctx := context.TODO()
 
 	cronHPAManifest := `
-apiVersion: cron-hpa.dtaniwaki.github.com/v1alpha1
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
 kind: CronHorizontalPodAutoscaler
 metadata:
   name: cron-hpa-sample
@@ -314,8 +1251,9 @@ spec:
 		t.FailNow()
 	}
 	reconciler := &CronHorizontalPodAutoscalerReconciler{
-		Client:   fakeClient,
-		Recorder: &test.FakeRecorder{},
+		Client:          fakeClient,
+		Recorder:        &test.FakeRecorder{},
+		HPAGroupVersion: gv,
 	}
 
 	// Create a CronHPA.
@@ -325,8 +1263,11 @@ spec:
 	}
 
 	// Ensure no HPA.
-	hpa := &autoscalingv2beta2.HorizontalPodAutoscaler{}
-	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, hpa)
+	obj, err := NewVersionedHPA(gv)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, obj)
 	if !assert.Equal(t, errors.IsNotFound(err), true) {
 		t.FailNow()
 	}
@@ -336,22 +1277,33 @@ spec:
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, hpa)
+	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	hpa, err := FromVersionedHPA(obj)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
 	if !assert.Equal(t, int32(1), *hpa.Spec.MinReplicas) {
 		t.FailNow()
 	}
+	if !assert.True(t, meta.IsStatusConditionTrue(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)) {
+		t.FailNow()
+	}
 
 	// Update an HPA.
-	newMinReplicas := int32(2)
+	newMinReplicas := intstr.FromInt(2)
 	cronhpa.Spec.ScheduledPatches[0].Patch.MinReplicas = &newMinReplicas
 	err = cronhpa.CreateOrPatchHPA(ctx, "weekday", currentTime, reconciler)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, hpa)
+	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	hpa, err = FromVersionedHPA(obj)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
@@ -360,31 +1312,103 @@ spec:
 	}
 
 	// Skip updating an HPA.
-	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, hpa)
+	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	hpa, err = FromVersionedHPA(obj)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
 	if !assert.Equal(t, int32(2), *hpa.Spec.MinReplicas) {
 		t.FailNow()
 	}
-	hpa.Annotations = map[string]string{
+	obj.SetAnnotations(map[string]string{
 		"cron-hpa.dtaniwaki.github.com/skip": "true",
-	}
-	err = reconciler.Client.Update(ctx, hpa)
+	})
+	err = reconciler.Client.Update(ctx, obj)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	newMinReplicas = int32(3)
+	newMinReplicas = intstr.FromInt(3)
 	cronhpa.Spec.ScheduledPatches[0].Patch.MinReplicas = &newMinReplicas
 	err = cronhpa.CreateOrPatchHPA(ctx, "weekday", currentTime, reconciler)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
-	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, hpa)
+	err = reconciler.Client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-sample"}, obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	hpa, err = FromVersionedHPA(obj)
 	if !assert.NoError(t, err) {
 		t.FailNow()
 	}
 	if !assert.Equal(t, int32(2), *hpa.Spec.MinReplicas) {
 		t.FailNow()
 	}
+	if !assert.True(t, meta.IsStatusConditionFalse(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)) {
+		t.FailNow()
+	}
+	if !assert.True(t, meta.IsStatusConditionTrue(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeSuspended)) {
+		t.FailNow()
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	cronhpa.Generation = 2
+
+	cronhpa.SetCondition(cronhpav1beta1.ConditionTypeHPASynced, metav1.ConditionTrue, "Updated", "Updated HPA")
+	if !assert.Equal(t, int64(2), cronhpa.Status.ObservedGeneration) {
+		t.FailNow()
+	}
+	condition := meta.FindStatusCondition(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)
+	if !assert.NotNil(t, condition) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int64(2), condition.ObservedGeneration) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "Updated", condition.Reason) {
+		t.FailNow()
+	}
+
+	cronhpa.Generation = 3
+	cronhpa.SetCondition(cronhpav1beta1.ConditionTypeHPASynced, metav1.ConditionFalse, "SyncFailed", "Failed")
+	if !assert.Len(t, cronhpa.Status.Conditions, 1) {
+		t.FailNow()
+	}
+	if !assert.True(t, meta.IsStatusConditionFalse(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int64(3), cronhpa.Status.Conditions[0].ObservedGeneration) {
+		t.FailNow()
+	}
+}
+
+func TestValidateSchedules(t *testing.T) {
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	cronhpa.Spec.ScheduledPatches = []cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch{
+		{Name: "daytime", Schedule: "0 8 * * *", Timezone: "Asia/Tokyo"},
+		{Name: "business", Schedule: "0 9 * * mon-fri", EndSchedule: "0 18 * * mon-fri"},
+	}
+	if !assert.NoError(t, cronhpa.ValidateSchedules()) {
+		t.FailNow()
+	}
+
+	cronhpa.Spec.ScheduledPatches[0].Timezone = "Invalid/Timezone"
+	if !assert.Error(t, cronhpa.ValidateSchedules()) {
+		t.FailNow()
+	}
+	cronhpa.Spec.ScheduledPatches[0].Timezone = "Asia/Tokyo"
+	cronhpa.Spec.ScheduledPatches[0].Schedule = "0 8 * *"
+	if !assert.Error(t, cronhpa.ValidateSchedules()) {
+		t.FailNow()
+	}
+	cronhpa.Spec.ScheduledPatches[0].Schedule = "0 8 * * *"
+	cronhpa.Spec.ScheduledPatches[1].EndSchedule = "invalid"
+	if !assert.Error(t, cronhpa.ValidateSchedules()) {
+		t.FailNow()
+	}
 }
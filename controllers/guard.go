@@ -0,0 +1,109 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+const defaultScaleDownGuardRetryInterval = time.Minute
+
+// getScaleDownGuardPolicy returns the policy of the scale-down guard with the default.
+func (cronhpa *CronHorizontalPodAutoscaler) getScaleDownGuardPolicy() string {
+	guard := cronhpa.Spec.ScaleDownGuard
+	if guard == nil || guard.Policy == "" {
+		return cronhpav1beta1.ScaleDownGuardPolicyDefer
+	}
+	return guard.Policy
+}
+
+// ApplyScaleDownGuard checks if the new HPA scales in the current HPA abruptly, and records the guarded patch
+// in the status to retry it. With the Clamp policy, it keeps the replicas of the new HPA as far as it's not safe.
+// It returns the guarded patch, or nil if the new HPA is safe to apply.
+func (cronhpa *CronHorizontalPodAutoscaler) ApplyScaleDownGuard(patchName string, currentTime time.Time, hpa, newhpa *autoscalingv2.HorizontalPodAutoscaler) *cronhpav1beta1.GuardedPatchStatus {
+	guard := cronhpa.Spec.ScaleDownGuard
+	if guard == nil || hpa == nil {
+		cronhpa.Status.GuardedPatch = nil
+		return nil
+	}
+	currentReplicas := hpa.Status.CurrentReplicas
+	belowReplicas := newhpa.Spec.MaxReplicas < currentReplicas
+	utilizationReason := getHighUtilizationReason(guard, hpa, newhpa)
+	var reason string
+	if belowReplicas {
+		reason = fmt.Sprintf("maxReplicas %d is below the current replicas %d", newhpa.Spec.MaxReplicas, currentReplicas)
+	} else if utilizationReason != "" {
+		reason = utilizationReason
+	} else {
+		cronhpa.Status.GuardedPatch = nil
+		return nil
+	}
+
+	if cronhpa.getScaleDownGuardPolicy() == cronhpav1beta1.ScaleDownGuardPolicyClamp {
+		if belowReplicas {
+			newhpa.Spec.MaxReplicas = currentReplicas
+		}
+		if utilizationReason != "" {
+			if newhpa.Spec.MaxReplicas < hpa.Spec.MaxReplicas {
+				newhpa.Spec.MaxReplicas = hpa.Spec.MaxReplicas
+			}
+			if hpa.Spec.MinReplicas != nil && (newhpa.Spec.MinReplicas == nil || *newhpa.Spec.MinReplicas < *hpa.Spec.MinReplicas) {
+				minReplicas := *hpa.Spec.MinReplicas
+				newhpa.Spec.MinReplicas = &minReplicas
+			}
+		}
+	}
+
+	retryInterval := defaultScaleDownGuardRetryInterval
+	if guard.RetryInterval != nil && guard.RetryInterval.Duration > 0 {
+		retryInterval = guard.RetryInterval.Duration
+	}
+	cronhpa.Status.GuardedPatch = &cronhpav1beta1.GuardedPatchStatus{
+		Name:      patchName,
+		Reason:    reason,
+		RetryTime: metav1.Time{Time: currentTime.Add(retryInterval)},
+	}
+	return cronhpa.Status.GuardedPatch
+}
+
+// getHighUtilizationReason returns why the new HPA is not safe to apply by the current utilization of the HPA,
+// or an empty string if the new HPA doesn't lower the replicas or the utilization is not above the threshold.
+func getHighUtilizationReason(guard *cronhpav1beta1.ScaleDownGuard, hpa, newhpa *autoscalingv2.HorizontalPodAutoscaler) string {
+	if guard.MaxUtilizationPercentage == nil {
+		return ""
+	}
+	lowersMinReplicas := hpa.Spec.MinReplicas != nil && (newhpa.Spec.MinReplicas == nil || *newhpa.Spec.MinReplicas < *hpa.Spec.MinReplicas)
+	if !lowersMinReplicas && newhpa.Spec.MaxReplicas >= hpa.Spec.MaxReplicas {
+		return ""
+	}
+	for _, metric := range hpa.Status.CurrentMetrics {
+		if metric.Type != autoscalingv2.ResourceMetricSourceType || metric.Resource == nil {
+			continue
+		}
+		utilization := metric.Resource.Current.AverageUtilization
+		if utilization != nil && *utilization > *guard.MaxUtilizationPercentage {
+			return fmt.Sprintf("%s utilization %d%% is above %d%%", metric.Resource.Name, *utilization, *guard.MaxUtilizationPercentage)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	"github.com/dtaniwaki/cron-hpa/test"
+)
+
+const guardCronHPAManifest = `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 2
+      maxReplicas: 20
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 10
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 1
+      maxReplicas: 5
+  scaleDownGuard:
+    maxUtilizationPercentage: 80
+`
+
+// setHPAStatus sets the current replicas and the current CPU utilization of the HPA.
+func setHPAStatus(hpa *autoscalingv2.HorizontalPodAutoscaler, currentReplicas, utilization int32) {
+	hpa.Status.CurrentReplicas = currentReplicas
+	hpa.Status.CurrentMetrics = []autoscalingv2.MetricStatus{{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricStatus{
+			Name:    corev1.ResourceCPU,
+			Current: autoscalingv2.MetricValueStatus{AverageUtilization: &utilization},
+		},
+	}}
+}
+
+func TestApplyScaleDownGuard(t *testing.T) {
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T22:00:00+09:00"))
+
+	for name, c := range map[string]struct {
+		currentReplicas int32
+		utilization     int32
+		guarded         bool
+		minReplicas     int32
+		maxReplicas     int32
+	}{
+		"safe":                {4, 50, false, 1, 5},
+		"below the replicas":  {8, 50, true, 1, 8},
+		"high utilization":    {4, 90, true, 10, 20},
+		"both of the reasons": {8, 90, true, 10, 20},
+	} {
+		cronhpa := &CronHorizontalPodAutoscaler{}
+		err := yaml.Unmarshal([]byte(guardCronHPAManifest), cronhpa.ToCompatible())
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		cronhpa.Spec.ScaleDownGuard.Policy = cronhpav1beta1.ScaleDownGuardPolicyClamp
+		hpa, err := cronhpa.NewHPA("daytime")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		setHPAStatus(hpa, c.currentReplicas, c.utilization)
+		newhpa, err := cronhpa.NewHPA("nighttime")
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+
+		guarded := cronhpa.ApplyScaleDownGuard("nighttime", currentTime, hpa, newhpa)
+		if !assert.Equal(t, c.guarded, guarded != nil, name) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.minReplicas, *newhpa.Spec.MinReplicas, name) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.maxReplicas, newhpa.Spec.MaxReplicas, name) {
+			t.FailNow()
+		}
+		if c.guarded && !assert.True(t, currentTime.Add(time.Minute).Equal(cronhpa.Status.GuardedPatch.RetryTime.Time), name) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestCreateOrPatchHPAWithScaleDownGuard(t *testing.T) {
+	ctx := context.TODO()
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(guardCronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	hpa, err := cronhpa.NewHPA("daytime")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	setHPAStatus(hpa, 8, 50)
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, clientgoscheme.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cronhpa.ToCompatible(), hpa).Build()
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:   fakeClient,
+		Recorder: &test.FakeRecorder{},
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T22:00:00+09:00"))
+	cronhpa.Status.LastScheduledPatchName = "daytime"
+
+	// Deferred while the replicas are above maxReplicas of the patch.
+	err = cronhpa.CreateOrPatchHPA(ctx, "nighttime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	saved := &autoscalingv2.HorizontalPodAutoscaler{}
+	err = fakeClient.Get(ctx, cronhpa.ToHPANamespacedName(), saved)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(20), saved.Spec.MaxReplicas) {
+		t.FailNow()
+	}
+	condition := meta.FindStatusCondition(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)
+	if !assert.Equal(t, conditionReasonScaleDownDeferred, condition.Reason) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "daytime", cronhpa.Status.LastScheduledPatchName) {
+		t.FailNow()
+	}
+	nextTime, err := cronhpa.GetNextReconcileTime(currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, currentTime.Add(time.Minute).Equal(nextTime), nextTime) {
+		t.FailNow()
+	}
+
+	// Applied once the replicas decrease.
+	setHPAStatus(saved, 4, 50)
+	err = fakeClient.Update(ctx, saved)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	currentTime = nextTime
+	err = cronhpa.CreateOrPatchHPA(ctx, "nighttime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = fakeClient.Get(ctx, cronhpa.ToHPANamespacedName(), saved)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(5), saved.Spec.MaxReplicas) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, cronhpa.Status.GuardedPatch) {
+		t.FailNow()
+	}
+}
+
+func TestCreateOrPatchHPAWithScaleDownGuardAndRamp(t *testing.T) {
+	ctx := context.TODO()
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(guardCronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cronhpa.Spec.ScheduledPatches[1].Ramp = &cronhpav1beta1.Ramp{Duration: metav1.Duration{Duration: 30 * time.Minute}, Steps: 3}
+	hpa, err := cronhpa.NewHPA("daytime")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	setHPAStatus(hpa, 8, 50)
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, clientgoscheme.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cronhpa.ToCompatible(), hpa).Build()
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:   fakeClient,
+		Recorder: &test.FakeRecorder{},
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T22:00:00+09:00"))
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{Time: currentTime.Add(-14 * time.Hour)}
+	cronhpa.Status.LastScheduledPatchName = "daytime"
+
+	err = cronhpa.CreateOrPatchHPA(ctx, "nighttime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, conditionReasonScaleDownDeferred, meta.FindStatusCondition(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced).Reason) {
+		t.FailNow()
+	}
+
+	// The deferred patch is still current, and ramps on the successful retry.
+	saved := &autoscalingv2.HorizontalPodAutoscaler{}
+	err = fakeClient.Get(ctx, cronhpa.ToHPANamespacedName(), saved)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	setHPAStatus(saved, 4, 50)
+	err = fakeClient.Update(ctx, saved)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	currentTime = currentTime.Add(20 * time.Minute)
+	patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "nighttime", patchName) {
+		t.FailNow()
+	}
+	err = cronhpa.CreateOrPatchHPA(ctx, patchName, currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = fakeClient.Get(ctx, cronhpa.ToHPANamespacedName(), saved)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(5), saved.Spec.MaxReplicas) {
+		t.FailNow()
+	}
+	// 2/3 of the way from 10 to 1.
+	if !assert.Equal(t, int32(4), *saved.Spec.MinReplicas) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "nighttime", cronhpa.Status.LastScheduledPatchName) {
+		t.FailNow()
+	}
+}
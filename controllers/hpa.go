@@ -0,0 +1,119 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// HPAGroupVersions are the HPA API versions CronHPA can manage in order of preference.
+var HPAGroupVersions = []schema.GroupVersion{
+	autoscalingv2.SchemeGroupVersion,
+	autoscalingv2beta2.SchemeGroupVersion,
+}
+
+// DetectHPAGroupVersion returns the most preferred HPA API version served by the cluster.
+func DetectHPAGroupVersion(discoveryClient discovery.DiscoveryInterface) (schema.GroupVersion, error) {
+	for _, gv := range HPAGroupVersions {
+		resources, err := discoveryClient.ServerResourcesForGroupVersion(gv.String())
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return schema.GroupVersion{}, err
+		}
+		for _, resource := range resources.APIResources {
+			if resource.Name == "horizontalpodautoscalers" {
+				return gv, nil
+			}
+		}
+	}
+	return schema.GroupVersion{}, fmt.Errorf("No supported HPA API version is served")
+}
+
+// NewVersionedHPA returns an empty HPA object of the given API version.
+func NewVersionedHPA(gv schema.GroupVersion) (client.Object, error) {
+	switch gv {
+	case autoscalingv2.SchemeGroupVersion:
+		return &autoscalingv2.HorizontalPodAutoscaler{}, nil
+	case autoscalingv2beta2.SchemeGroupVersion:
+		return &autoscalingv2beta2.HorizontalPodAutoscaler{}, nil
+	}
+	return nil, fmt.Errorf("Unsupported HPA API version %s", gv)
+}
+
+// NewVersionedHPAList returns an empty HPA list object of the given API version.
+func NewVersionedHPAList(gv schema.GroupVersion) (client.ObjectList, error) {
+	switch gv {
+	case autoscalingv2.SchemeGroupVersion:
+		return &autoscalingv2.HorizontalPodAutoscalerList{}, nil
+	case autoscalingv2beta2.SchemeGroupVersion:
+		return &autoscalingv2beta2.HorizontalPodAutoscalerList{}, nil
+	}
+	return nil, fmt.Errorf("Unsupported HPA API version %s", gv)
+}
+
+// ToVersionedHPA converts an autoscaling/v2 HPA to the given API version.
+// autoscaling/v2 and autoscaling/v2beta2 share the same schema, so the conversion goes through JSON.
+func ToVersionedHPA(hpa *autoscalingv2.HorizontalPodAutoscaler, gv schema.GroupVersion) (client.Object, error) {
+	if gv == autoscalingv2.SchemeGroupVersion {
+		return hpa, nil
+	}
+	obj, err := NewVersionedHPA(gv)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(hpa)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+	obj.GetObjectKind().SetGroupVersionKind(gv.WithKind("HorizontalPodAutoscaler"))
+	return obj, nil
+}
+
+// FromVersionedHPA converts an HPA of any supported API version to autoscaling/v2.
+func FromVersionedHPA(obj client.Object) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	switch o := obj.(type) {
+	case *autoscalingv2.HorizontalPodAutoscaler:
+		return o, nil
+	case *autoscalingv2beta2.HorizontalPodAutoscaler:
+		data, err := json.Marshal(o)
+		if err != nil {
+			return nil, err
+		}
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		if err := json.Unmarshal(data, hpa); err != nil {
+			return nil, err
+		}
+		if hpa.APIVersion != "" {
+			hpa.APIVersion = autoscalingv2.SchemeGroupVersion.Identifier()
+		}
+		return hpa, nil
+	}
+	return nil, fmt.Errorf("Unsupported HPA object %T", obj)
+}
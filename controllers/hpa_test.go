@@ -0,0 +1,137 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	autoscalingv2beta2 "k8s.io/api/autoscaling/v2beta2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/yaml"
+)
+
+func TestDetectHPAGroupVersion(t *testing.T) {
+	hpaResources := []metav1.APIResource{{Name: "horizontalpodautoscalers", Kind: "HorizontalPodAutoscaler"}}
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+
+	// Both versions served.
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "autoscaling/v2beta2", APIResources: hpaResources},
+		{GroupVersion: "autoscaling/v2", APIResources: hpaResources},
+	}
+	gv, err := DetectHPAGroupVersion(discoveryClient)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, autoscalingv2.SchemeGroupVersion, gv) {
+		t.FailNow()
+	}
+
+	// Only v2beta2 served.
+	discoveryClient.Resources = []*metav1.APIResourceList{
+		{GroupVersion: "autoscaling/v2beta2", APIResources: hpaResources},
+	}
+	gv, err = DetectHPAGroupVersion(discoveryClient)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, autoscalingv2beta2.SchemeGroupVersion, gv) {
+		t.FailNow()
+	}
+
+	// No version served.
+	discoveryClient.Resources = []*metav1.APIResourceList{}
+	_, err = DetectHPAGroupVersion(discoveryClient)
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+}
+
+func TestToVersionedHPA(t *testing.T) {
+	hpaManifest := `
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+  annotations:
+    foo: bar
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: cron-hpa-nginx
+  minReplicas: 1
+  maxReplicas: 10
+  metrics:
+  - type: Resource
+    resource:
+      name: cpu
+      target:
+        type: Utilization
+        averageUtilization: 50
+  behavior:
+    scaleDown:
+      stabilizationWindowSeconds: 300
+      policies:
+      - type: Pods
+        value: 1
+        periodSeconds: 60
+`
+
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(hpaManifest), hpa)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// autoscaling/v2 is kept as is.
+	obj, err := ToVersionedHPA(hpa, autoscalingv2.SchemeGroupVersion)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, hpa, obj) {
+		t.FailNow()
+	}
+
+	// autoscaling/v2beta2 round-trips.
+	obj, err = ToVersionedHPA(hpa, autoscalingv2beta2.SchemeGroupVersion)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	v2beta2HPA, ok := obj.(*autoscalingv2beta2.HorizontalPodAutoscaler)
+	if !assert.True(t, ok) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "autoscaling/v2beta2", v2beta2HPA.APIVersion) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(300), *v2beta2HPA.Spec.Behavior.ScaleDown.StabilizationWindowSeconds) {
+		t.FailNow()
+	}
+	restored, err := FromVersionedHPA(obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, hpa, restored) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,250 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+// ScaledObjectGroupVersionKind is the kind of KEDA ScaledObjects.
+// ScaledObjects are handled as unstructured objects not to depend on KEDA.
+var ScaledObjectGroupVersionKind = schema.GroupVersionKind{Group: "keda.sh", Version: "v1alpha1", Kind: "ScaledObject"}
+
+// NewScaledObject returns an empty KEDA ScaledObject.
+func NewScaledObject() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(ScaledObjectGroupVersionKind)
+	return obj
+}
+
+// GetBaselineScaledObjectSpec returns the baseline spec of the ScaledObject, or nil if it's not captured yet.
+func (cronhpa *CronHorizontalPodAutoscaler) GetBaselineScaledObjectSpec() (map[string]interface{}, error) {
+	if cronhpa.Status.BaselineScaledObjectSpec == nil {
+		return nil, nil
+	}
+	spec := map[string]interface{}{}
+	// The JSON decoder of apimachinery decodes integers as int64 like unstructured objects.
+	if err := utiljson.Unmarshal(cronhpa.Status.BaselineScaledObjectSpec.Raw, &spec); err != nil {
+		return nil, fmt.Errorf("Invalid baseline of ScaledObject: %w", err)
+	}
+	return spec, nil
+}
+
+// UpdateBaselineScaledObjectSpec captures the spec of the ScaledObject as the baseline when it's referred first,
+// or when it's changed by others while no patch is applied. It returns true if the baseline changes.
+func (cronhpa *CronHorizontalPodAutoscaler) UpdateBaselineScaledObjectSpec(spec map[string]interface{}) (bool, error) {
+	baseline, err := cronhpa.GetBaselineScaledObjectSpec()
+	if err != nil {
+		return false, err
+	}
+	if baseline != nil && (cronhpa.Status.LastScheduledPatchName != "" || reflect.DeepEqual(baseline, spec)) {
+		return false, nil
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return false, err
+	}
+	cronhpa.Status.BaselineScaledObjectSpec = &apiextensionsv1.JSON{Raw: data}
+	return true, nil
+}
+
+// NewScaledObjectSpec returns the baseline spec of the ScaledObject with the patch.
+func (cronhpa *CronHorizontalPodAutoscaler) NewScaledObjectSpec(patchName string) (map[string]interface{}, error) {
+	spec, err := cronhpa.GetBaselineScaledObjectSpec()
+	if err != nil {
+		return nil, err
+	}
+	if spec == nil {
+		return nil, fmt.Errorf("No baseline of ScaledObject %s", cronhpa.Spec.ScaledObjectRef.Name)
+	}
+	if patchName == "" {
+		return spec, nil
+	}
+	var scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch
+	for _, sp := range cronhpa.Spec.ScheduledPatches {
+		if sp.Name == patchName {
+			scheduledPatch = &sp
+			break
+		}
+	}
+	if scheduledPatch == nil {
+		return nil, fmt.Errorf("No schedule patch named %s", patchName)
+	}
+	if scheduledPatch.ScaledObjectPatch != nil {
+		if err := applyScaledObjectPatch(spec, scheduledPatch.ScaledObjectPatch); err != nil {
+			return nil, fmt.Errorf("Failed to apply patches of %s: %w", patchName, err)
+		}
+	}
+	return spec, nil
+}
+
+func applyScaledObjectPatch(spec map[string]interface{}, patch *cronhpav1beta1.ScaledObjectPatch) error {
+	if patch.MinReplicaCount != nil {
+		spec["minReplicaCount"] = int64(*patch.MinReplicaCount)
+	}
+	if patch.MaxReplicaCount != nil {
+		spec["maxReplicaCount"] = int64(*patch.MaxReplicaCount)
+	}
+	if len(patch.Triggers) == 0 {
+		return nil
+	}
+	triggers, _, err := unstructured.NestedSlice(spec, "triggers")
+	if err != nil {
+		return err
+	}
+	for _, triggerPatch := range patch.Triggers {
+		matched := false
+		for i := range triggers {
+			trigger, ok := triggers[i].(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("Invalid trigger %d", i)
+			}
+			if triggerPatch.Name != "" && trigger["name"] != triggerPatch.Name {
+				continue
+			}
+			if triggerPatch.Name == "" && trigger["type"] != triggerPatch.Type {
+				continue
+			}
+			matched = true
+			metadata, _, err := unstructured.NestedMap(trigger, "metadata")
+			if err != nil {
+				return err
+			}
+			if metadata == nil {
+				metadata = map[string]interface{}{}
+			}
+			for key, value := range triggerPatch.Metadata {
+				metadata[key] = value
+			}
+			trigger["metadata"] = metadata
+		}
+		if !matched {
+			return fmt.Errorf("No trigger matched with name %q and type %q", triggerPatch.Name, triggerPatch.Type)
+		}
+	}
+	spec["triggers"] = triggers
+	return nil
+}
+
+// PatchScaledObject applies the patch to the ScaledObject on top of its baseline.
+func (cronhpa *CronHorizontalPodAutoscaler) PatchScaledObject(ctx context.Context, patchName string, currentTime time.Time, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	logger := log.FromContext(ctx)
+
+	name := cronhpa.Spec.ScaledObjectRef.Name
+	obj := NewScaledObject()
+	if err := reconciler.Get(ctx, types.NamespacedName{Namespace: cronhpa.Namespace, Name: name}, obj); err != nil {
+		if errors.IsNotFound(err) {
+			return fmt.Errorf("No ScaledObject %s to patch", name)
+		}
+		return err
+	}
+	skipped, err := cronhpa.IsSkipped(ctx, obj, currentTime, reconciler)
+	if err != nil {
+		return err
+	}
+	spec, _, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		return err
+	}
+	updated, err := cronhpa.UpdateBaselineScaledObjectSpec(spec)
+	if err != nil {
+		return err
+	}
+	if updated {
+		logger.Info(fmt.Sprintf("Captured the baseline of ScaledObject %s", name))
+	}
+	newSpec, err := cronhpa.NewScaledObjectSpec(patchName)
+	if err != nil {
+		return err
+	}
+
+	event := ""
+	msg := ""
+	syncedStatus, syncedReason := metav1.ConditionTrue, ""
+	suspended := false
+	if skipped {
+		logger.Info("Skip updating a ScaledObject by an annotation")
+		event = CronHPAEventSkipped
+		msg = "Skipped updating ScaledObject by an annotation"
+		syncedStatus, syncedReason = metav1.ConditionFalse, conditionReasonSkipped
+		suspended = true
+	} else if reflect.DeepEqual(spec, newSpec) {
+		logger.Info("Skip updating a ScaledObject with no changes")
+		event = CronHPAEventSkipped
+		msg = "Skipped updating ScaledObject with no changes"
+		syncedReason = conditionReasonNoChanges
+	} else {
+		newobj := obj.DeepCopy()
+		newobj.Object["spec"] = runtime.DeepCopyJSON(newSpec)
+		if err := reconciler.Patch(ctx, newobj, client.MergeFrom(obj)); err != nil {
+			return err
+		}
+		logger.Info("Updated a ScaledObject successfully")
+		event = CronHPAEventUpdated
+		msg = "Updated ScaledObject"
+		syncedReason = conditionReasonUpdated
+	}
+	return cronhpa.updateSyncStatus(ctx, patchName, currentTime, event, msg, syncedStatus, syncedReason, suspended, reconciler)
+}
+
+// restoreBaselineScaledObject restores the baseline spec of the ScaledObject.
+func (cronhpa *CronHorizontalPodAutoscaler) restoreBaselineScaledObject(ctx context.Context, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	logger := log.FromContext(ctx)
+
+	if cronhpa.Status.BaselineScaledObjectSpec == nil {
+		return nil
+	}
+	obj := NewScaledObject()
+	if err := reconciler.Get(ctx, types.NamespacedName{Namespace: cronhpa.Namespace, Name: cronhpa.Spec.ScaledObjectRef.Name}, obj); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	spec, _, err := unstructured.NestedMap(obj.Object, "spec")
+	if err != nil {
+		return err
+	}
+	baseline, err := cronhpa.GetBaselineScaledObjectSpec()
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(spec, baseline) {
+		return nil
+	}
+	newobj := obj.DeepCopy()
+	newobj.Object["spec"] = baseline
+	if err := reconciler.Patch(ctx, newobj, client.MergeFrom(obj)); err != nil {
+		return err
+	}
+	logger.Info(fmt.Sprintf("Restored the baseline of ScaledObject %s", obj.GetName()))
+	return nil
+}
@@ -0,0 +1,241 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	"github.com/dtaniwaki/cron-hpa/test"
+)
+
+const kedaCronHPAManifest = `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  scaledObjectRef:
+    name: cron-hpa-nginx
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    scaledObjectPatch:
+      minReplicaCount: 5
+      maxReplicaCount: 20
+      triggers:
+      - type: prometheus
+        metadata:
+          threshold: "50"
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+`
+
+const kedaScaledObjectManifest = `
+apiVersion: keda.sh/v1alpha1
+kind: ScaledObject
+metadata:
+  name: cron-hpa-nginx
+  namespace: default
+spec:
+  scaleTargetRef:
+    name: cron-hpa-nginx
+  minReplicaCount: 1
+  maxReplicaCount: 10
+  triggers:
+  - type: prometheus
+    metadata:
+      serverAddress: http://prometheus:9090
+      query: sum(rate(http_requests_total[1m]))
+      threshold: "100"
+  - type: cpu
+    name: cpu
+    metadata:
+      type: Utilization
+      value: "60"
+`
+
+func newKEDAObjects(t *testing.T) (*CronHorizontalPodAutoscaler, *unstructured.Unstructured) {
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	if err := yaml.Unmarshal([]byte(kedaCronHPAManifest), cronhpa.ToCompatible()); err != nil {
+		t.Fatal(err)
+	}
+	obj := NewScaledObject()
+	if err := yaml.Unmarshal([]byte(kedaScaledObjectManifest), &obj.Object); err != nil {
+		t.Fatal(err)
+	}
+	return cronhpa, obj
+}
+
+func assertScaledObject(t *testing.T, ctx context.Context, c client.Client, minReplicaCount, maxReplicaCount int64, threshold string) {
+	obj := NewScaledObject()
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-nginx"}, obj); err != nil {
+		t.Fatal(err)
+	}
+	if !assert.Equal(t, minReplicaCount, obj.Object["spec"].(map[string]interface{})["minReplicaCount"]) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, maxReplicaCount, obj.Object["spec"].(map[string]interface{})["maxReplicaCount"]) {
+		t.FailNow()
+	}
+	triggers, _, _ := unstructured.NestedSlice(obj.Object, "spec", "triggers")
+	if !assert.Equal(t, threshold, triggers[0].(map[string]interface{})["metadata"].(map[string]interface{})["threshold"]) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "60", triggers[1].(map[string]interface{})["metadata"].(map[string]interface{})["value"]) {
+		t.FailNow()
+	}
+}
+
+func TestPatchScaledObject(t *testing.T) {
+	ctx := context.TODO()
+
+	cronhpa, obj := newKEDAObjects(t)
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cronhpa.ToCompatible(), obj).Build()
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:   fakeClient,
+		Recorder: &test.FakeRecorder{},
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T08:00:00+09:00"))
+
+	// Apply the patch on top of the captured baseline.
+	err := cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assertScaledObject(t, ctx, fakeClient, 5, 20, "50")
+	if !assert.NotNil(t, cronhpa.Status.BaselineScaledObjectSpec) {
+		t.FailNow()
+	}
+	baseline, err := cronhpa.GetBaselineScaledObjectSpec()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int64(1), baseline["minReplicaCount"]) {
+		t.FailNow()
+	}
+	condition := meta.FindStatusCondition(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)
+	if !assert.Equal(t, conditionReasonUpdated, condition.Reason) {
+		t.FailNow()
+	}
+
+	// No changes.
+	err = cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	condition = meta.FindStatusCondition(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)
+	if !assert.Equal(t, conditionReasonNoChanges, condition.Reason) {
+		t.FailNow()
+	}
+
+	// Restore the baseline by a patch without scaledObjectPatch.
+	err = cronhpa.CreateOrPatchHPA(ctx, "nighttime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assertScaledObject(t, ctx, fakeClient, 1, 10, "100")
+
+	// Skip by the annotation.
+	obj = NewScaledObject()
+	err = fakeClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "cron-hpa-nginx"}, obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	obj.SetAnnotations(map[string]string{annotationNameSkip: "true"})
+	err = fakeClient.Update(ctx, obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assertScaledObject(t, ctx, fakeClient, 1, 10, "100")
+	if !assert.True(t, meta.IsStatusConditionTrue(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeSuspended)) {
+		t.FailNow()
+	}
+
+	// No matched trigger.
+	cronhpa.Spec.ScheduledPatches[0].ScaledObjectPatch.Triggers[0].Type = "kafka"
+	_, err = cronhpa.NewScaledObjectSpec("daytime")
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+}
+
+func TestPatchScaledObjectWithEnvtest(t *testing.T) {
+	ctx := context.TODO()
+
+	cronhpa, obj := newKEDAObjects(t)
+
+	fakeClient, err := test.NewFakeClient(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	err = fakeClient.Create(ctx, cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = fakeClient.Create(ctx, obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:   fakeClient,
+		Recorder: &test.FakeRecorder{},
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T08:00:00+09:00"))
+
+	err = cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assertScaledObject(t, ctx, fakeClient, 5, 20, "50")
+
+	// Restore the baseline on deletion.
+	err = cronhpa.RestoreBaselineHPA(ctx, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assertScaledObject(t, ctx, fakeClient, 1, 10, "100")
+}
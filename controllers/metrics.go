@@ -0,0 +1,110 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+// metricKey returns the identity of a metric by the type and the name, the container, the selector
+// and the described object.
+func metricKey(metricType autoscalingv2.MetricSourceType, name, container string, selector *metav1.LabelSelector, describedObject *autoscalingv2.CrossVersionObjectReference) string {
+	selectorKey := ""
+	if selector != nil && (len(selector.MatchLabels) > 0 || len(selector.MatchExpressions) > 0) {
+		selectorKey = metav1.FormatLabelSelector(selector)
+	}
+	objectKey := ""
+	if describedObject != nil {
+		objectKey = fmt.Sprintf("%s/%s/%s", describedObject.APIVersion, describedObject.Kind, describedObject.Name)
+	}
+	return fmt.Sprintf("%s|%s|%s|%s|%s", metricType, name, container, selectorKey, objectKey)
+}
+
+// metricSpecKey returns the identity of the metric spec.
+func metricSpecKey(metric *autoscalingv2.MetricSpec) string {
+	switch {
+	case metric.Resource != nil && metric.Type == autoscalingv2.ResourceMetricSourceType:
+		return metricKey(metric.Type, string(metric.Resource.Name), "", nil, nil)
+	case metric.ContainerResource != nil && metric.Type == autoscalingv2.ContainerResourceMetricSourceType:
+		return metricKey(metric.Type, string(metric.ContainerResource.Name), metric.ContainerResource.Container, nil, nil)
+	case metric.Pods != nil && metric.Type == autoscalingv2.PodsMetricSourceType:
+		return metricKey(metric.Type, metric.Pods.Metric.Name, "", metric.Pods.Metric.Selector, nil)
+	case metric.Object != nil && metric.Type == autoscalingv2.ObjectMetricSourceType:
+		return metricKey(metric.Type, metric.Object.Metric.Name, "", metric.Object.Metric.Selector, &metric.Object.DescribedObject)
+	case metric.External != nil && metric.Type == autoscalingv2.ExternalMetricSourceType:
+		return metricKey(metric.Type, metric.External.Metric.Name, "", metric.External.Metric.Selector, nil)
+	}
+	return metricKey(metric.Type, "", "", nil, nil)
+}
+
+// metricIdentityKey returns the identity of the metric to remove.
+func metricIdentityKey(identity *cronhpav1beta1.MetricIdentity) string {
+	return metricKey(identity.Type, identity.Name, identity.Container, identity.Selector, identity.DescribedObject)
+}
+
+// setMetricTarget overrides the target of the metric with the target of the other of the same identity.
+func setMetricTarget(metric *autoscalingv2.MetricSpec, other *autoscalingv2.MetricSpec) {
+	switch {
+	case metric.Resource != nil && other.Resource != nil:
+		metric.Resource.Target = other.Resource.Target
+	case metric.ContainerResource != nil && other.ContainerResource != nil:
+		metric.ContainerResource.Target = other.ContainerResource.Target
+	case metric.Pods != nil && other.Pods != nil:
+		metric.Pods.Target = other.Pods.Target
+	case metric.Object != nil && other.Object != nil:
+		metric.Object.Target = other.Object.Target
+	case metric.External != nil && other.External != nil:
+		metric.External.Target = other.External.Target
+	default:
+		*metric = *other.DeepCopy()
+	}
+}
+
+// mergeHPAMetrics merges the metrics of the patch into the metrics by identity. The targets of the metrics
+// of the same identity are overridden, the other metrics of the patch are appended, and the metrics
+// in RemoveMetrics are removed.
+func mergeHPAMetrics(metrics []autoscalingv2.MetricSpec, patch *cronhpav1beta1.HPAPatch) []autoscalingv2.MetricSpec {
+	removed := make(map[string]bool, len(patch.RemoveMetrics))
+	for i := range patch.RemoveMetrics {
+		removed[metricIdentityKey(&patch.RemoveMetrics[i])] = true
+	}
+	merged := make([]autoscalingv2.MetricSpec, 0, len(metrics)+len(patch.Metrics))
+	indexes := make(map[string]int, len(metrics))
+	for _, metric := range metrics {
+		key := metricSpecKey(&metric)
+		if removed[key] {
+			continue
+		}
+		indexes[key] = len(merged)
+		merged = append(merged, *metric.DeepCopy())
+	}
+	for _, metric := range patch.Metrics {
+		key := metricSpecKey(&metric)
+		if i, ok := indexes[key]; ok {
+			setMetricTarget(&merged[i], &metric)
+			continue
+		}
+		indexes[key] = len(merged)
+		merged = append(merged, *metric.DeepCopy())
+	}
+	return merged
+}
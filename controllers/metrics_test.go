@@ -0,0 +1,167 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"sigs.k8s.io/yaml"
+)
+
+func TestNewHPAWithMergedMetrics(t *testing.T) {
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+      metrics:
+      - type: Resource
+        resource:
+          name: cpu
+          target:
+            type: Utilization
+            averageUtilization: 50
+      - type: Resource
+        resource:
+          name: memory
+          target:
+            type: Utilization
+            averageUtilization: 60
+      - type: External
+        external:
+          metric:
+            name: queue_length
+            selector:
+              matchLabels:
+                queue: jobs
+          target:
+            type: AverageValue
+            averageValue: "30"
+  scheduledPatches:
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      metricsMergeMode: Merge
+      metrics:
+      - type: Resource
+        resource:
+          name: cpu
+          target:
+            type: Utilization
+            averageUtilization: 80
+      - type: External
+        external:
+          metric:
+            name: queue_length
+            selector:
+              matchLabels:
+                queue: jobs
+          target:
+            type: AverageValue
+            averageValue: "100"
+      - type: External
+        external:
+          metric:
+            name: queue_length
+            selector:
+              matchLabels:
+                queue: batches
+          target:
+            type: AverageValue
+            averageValue: "10"
+      removeMetrics:
+      - type: Resource
+        name: memory
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      metrics:
+      - type: Resource
+        resource:
+          name: cpu
+          target:
+            type: Utilization
+            averageUtilization: 40
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	hpa, err := cronhpa.NewHPA("nighttime")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	metrics := hpa.Spec.Metrics
+	if !assert.Len(t, metrics, 3) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "cpu", string(metrics[0].Resource.Name)) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(80), *metrics[0].Resource.Target.AverageUtilization) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, autoscalingv2.ExternalMetricSourceType, metrics[1].Type) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "jobs", metrics[1].External.Metric.Selector.MatchLabels["queue"]) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "100", metrics[1].External.Target.AverageValue.String()) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "batches", metrics[2].External.Metric.Selector.MatchLabels["queue"]) {
+		t.FailNow()
+	}
+
+	// The template is not changed.
+	if !assert.Len(t, cronhpa.Spec.Template.Spec.Metrics, 3) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(50), *cronhpa.Spec.Template.Spec.Metrics[0].Resource.Target.AverageUtilization) {
+		t.FailNow()
+	}
+
+	// Replace the whole list by default.
+	hpa, err = cronhpa.NewHPA("daytime")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Len(t, hpa.Spec.Metrics, 1) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(40), *hpa.Spec.Metrics[0].Resource.Target.AverageUtilization) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+// getMissedSchedulePolicy returns the missed schedule policy of the scheduled patch with the default.
+func getMissedSchedulePolicy(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch) string {
+	if scheduledPatch.MissedSchedulePolicy == "" {
+		return cronhpav1beta1.MissedSchedulePolicyApplyLatest
+	}
+	return scheduledPatch.MissedSchedulePolicy
+}
+
+// isMissedPatch returns true if the start of the patch at the given time is recorded as missed.
+func (cronhpa *CronHorizontalPodAutoscaler) isMissedPatch(patchName string, startTime time.Time) bool {
+	for _, missedPatch := range cronhpa.Status.MissedPatches {
+		if missedPatch.Name == patchName && missedPatch.StartTime.Time.Equal(startTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateMissedPatches records the latest starts of the patches which the controller missed beyond their starting
+// deadlines since the last cron timestamp, and emits a warning event for each newly missed start.
+// The records are kept while the starts are the latest so that the missed windows are handled until they close.
+// The status is saved with the status updated by CreateOrPatchHPA.
+func (cronhpa *CronHorizontalPodAutoscaler) UpdateMissedPatches(ctx context.Context, currentTime time.Time, calendar ExclusionCalendar, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	logger := log.FromContext(ctx)
+
+	lastCronTimestamp := cronhpa.Status.LastCronTimestamp
+	var missedPatches []cronhpav1beta1.MissedPatchStatus
+	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		scheduledPatch := scheduledPatch
+		if scheduledPatch.StartingDeadlineSeconds == nil {
+			continue
+		}
+		startTime, err := getLatestStartTime(&scheduledPatch, lastCronTimestamp, currentTime, calendar)
+		if err != nil {
+			return err
+		}
+		if startTime.IsZero() {
+			continue
+		}
+		if cronhpa.isMissedPatch(scheduledPatch.Name, startTime) {
+			missedPatches = append(missedPatches, cronhpav1beta1.MissedPatchStatus{Name: scheduledPatch.Name, StartTime: metav1.Time{Time: startTime}})
+			continue
+		}
+		// The start observed by the controller is not missed, including the one deferred by the scale-down guard.
+		if lastCronTimestamp == nil || !startTime.After(lastCronTimestamp.Time) {
+			continue
+		}
+		if guardedPatch := cronhpa.Status.GuardedPatch; guardedPatch != nil && guardedPatch.Name == scheduledPatch.Name {
+			continue
+		}
+		deadline := time.Duration(*scheduledPatch.StartingDeadlineSeconds) * time.Second
+		delay := currentTime.Sub(startTime)
+		if delay <= deadline {
+			continue
+		}
+		missedPatches = append(missedPatches, cronhpav1beta1.MissedPatchStatus{Name: scheduledPatch.Name, StartTime: metav1.Time{Time: startTime}})
+
+		var action string
+		switch getMissedSchedulePolicy(&scheduledPatch) {
+		case cronhpav1beta1.MissedSchedulePolicySkipIfMissed:
+			action = "skipped it"
+		case cronhpav1beta1.MissedSchedulePolicyApplyTemplate:
+			action = "applying the template instead"
+		default:
+			action = "applying it anyway"
+		}
+		msg := fmt.Sprintf("Missed %s started at %s by %s beyond the starting deadline %s, %s", scheduledPatch.Name, startTime.Format(time.RFC3339), delay.Round(time.Second), deadline, action)
+		logger.Info(msg)
+		reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeWarning, CronHPAEventMissed, msg)
+	}
+	cronhpa.Status.MissedPatches = missedPatches
+	return nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/yaml"
+)
+
+func TestUpdateMissedPatches(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: morning
+    schedule: "0 6 * * *"
+    timezone: "Asia/Tokyo"
+    startingDeadlineSeconds: 600
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    startingDeadlineSeconds: 600
+    missedSchedulePolicy: SkipIfMissed
+  - name: lunch
+    schedule: "0 12 * * *"
+    duration: 2h
+    timezone: "Asia/Tokyo"
+    startingDeadlineSeconds: 600
+    missedSchedulePolicy: ApplyTemplate
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+`
+
+	parseTime := func(s string) time.Time {
+		t := time.Time{}
+		_ = t.UnmarshalText([]byte(s))
+		return t
+	}
+
+	for _, c := range []struct {
+		lastCronTimestamp      string
+		lastScheduledPatchName string
+		currentTime            string
+		patchName              string
+		missed                 bool
+	}{
+		// Applied within the deadline.
+		{"2021-10-04T07:00:00+09:00", "morning", "2021-10-04T08:05:00+09:00", "daytime", false},
+		// Applied anyway.
+		{"2021-10-04T05:00:00+09:00", "nighttime", "2021-10-04T07:00:00+09:00", "morning", true},
+		// Skipped.
+		{"2021-10-04T07:00:00+09:00", "morning", "2021-10-04T09:00:00+09:00", "morning", true},
+		// Reverted to the template.
+		{"2021-10-04T11:00:00+09:00", "daytime", "2021-10-04T12:30:00+09:00", "", true},
+		// Without the last cron timestamp.
+		{"", "", "2021-10-04T12:30:00+09:00", "lunch", false},
+	} {
+		cronhpa := &CronHorizontalPodAutoscaler{}
+		err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if c.lastCronTimestamp != "" {
+			cronhpa.Status.LastCronTimestamp = &metav1.Time{Time: parseTime(c.lastCronTimestamp)}
+		}
+		cronhpa.Status.LastScheduledPatchName = c.lastScheduledPatchName
+		recorder := record.NewFakeRecorder(10)
+		reconciler := &CronHorizontalPodAutoscalerReconciler{Recorder: recorder}
+
+		currentTime := parseTime(c.currentTime)
+		err = cronhpa.UpdateMissedPatches(ctx, currentTime, nil, reconciler)
+		if !assert.NoError(t, err, c.currentTime) {
+			t.FailNow()
+		}
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+		if !assert.NoError(t, err, c.currentTime) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.missed, len(cronhpa.Status.MissedPatches) > 0, c.currentTime) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.missed, len(recorder.Events) > 0, c.currentTime) {
+			t.FailNow()
+		}
+		if c.missed && !assert.True(t, strings.HasPrefix(<-recorder.Events, "Warning Missed"), c.currentTime) {
+			t.FailNow()
+		}
+	}
+
+	// Keep the missed window until it closes.
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &CronHorizontalPodAutoscalerReconciler{Recorder: recorder}
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{Time: parseTime("2021-10-04T11:00:00+09:00")}
+	err = cronhpa.UpdateMissedPatches(ctx, parseTime("2021-10-04T12:30:00+09:00"), nil, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	// The status is saved with the template applied.
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{Time: parseTime("2021-10-04T12:30:00+09:00")}
+	cronhpa.Status.LastScheduledPatchName = ""
+	for _, c := range []struct {
+		currentTime string
+		missed      bool
+	}{
+		{"2021-10-04T13:00:00+09:00", true},
+		{"2021-10-04T14:30:00+09:00", false},
+	} {
+		currentTime := parseTime(c.currentTime)
+		err = cronhpa.UpdateMissedPatches(ctx, currentTime, nil, reconciler)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, "", patchName, c.currentTime) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.missed, len(cronhpa.Status.MissedPatches) > 0, c.currentTime) {
+			t.FailNow()
+		}
+	}
+	// Only the first miss is reported.
+	if !assert.Len(t, recorder.Events, 1) {
+		t.FailNow()
+	}
+}
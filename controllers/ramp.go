@@ -0,0 +1,129 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+// ApplyRamp sets minReplicas of the new HPA to the value on the way of the ramp of the patch at the given time.
+// A ramp starts from minReplicas of the current HPA when the patch with the ramp becomes active,
+// and is recorded in the status to resume at the same step after a restart.
+func (cronhpa *CronHorizontalPodAutoscaler) ApplyRamp(patchName string, currentTime time.Time, hpa, newhpa *autoscalingv2.HorizontalPodAutoscaler) error {
+	scheduledPatch := cronhpa.getScheduledPatch(patchName)
+	if scheduledPatch == nil || scheduledPatch.Ramp == nil || newhpa.Spec.MinReplicas == nil {
+		cronhpa.Status.Ramp = nil
+		return nil
+	}
+	ramp := cronhpa.Status.Ramp
+	if ramp == nil || ramp.PatchName != patchName {
+		// The patch had been applied before the ramp was added, or the HPA is created.
+		if cronhpa.Status.LastScheduledPatchName == patchName || hpa == nil {
+			cronhpa.Status.Ramp = nil
+			return nil
+		}
+		startTime, err := cronhpa.getPatchStartTime(scheduledPatch, currentTime)
+		if err != nil {
+			return err
+		}
+		fromMinReplicas := int32(1)
+		if hpa.Spec.MinReplicas != nil {
+			fromMinReplicas = *hpa.Spec.MinReplicas
+		}
+		ramp = &cronhpav1beta1.RampStatus{PatchName: patchName, StartTime: metav1.Time{Time: startTime}, FromMinReplicas: fromMinReplicas}
+		cronhpa.Status.Ramp = ramp
+	}
+	step := getRampStep(scheduledPatch.Ramp, ramp.StartTime.Time, currentTime)
+	steps := int64(scheduledPatch.Ramp.Steps)
+	if step >= steps {
+		return nil
+	}
+	from := int64(ramp.FromMinReplicas)
+	to := int64(*newhpa.Spec.MinReplicas)
+	minReplicas := int32(from + (to-from)*step/steps)
+	if minReplicas > newhpa.Spec.MaxReplicas {
+		minReplicas = newhpa.Spec.MaxReplicas
+	}
+	if minReplicas < 1 {
+		minReplicas = 1
+	}
+	newhpa.Spec.MinReplicas = &minReplicas
+	return nil
+}
+
+// getPatchStartTime returns the time when the active patch started, which is the latest schedule in the ramp
+// duration, or the given time if the patch is triggered manually or there is no such schedule.
+func (cronhpa *CronHorizontalPodAutoscaler) getPatchStartTime(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, currentTime time.Time) (time.Time, error) {
+	if triggeredPatch := cronhpa.Status.TriggeredPatch; triggeredPatch != nil && triggeredPatch.Name == scheduledPatch.Name {
+		return triggeredPatch.TriggeredTime.Time, nil
+	}
+	from := currentTime.Add(-scheduledPatch.Ramp.Duration.Duration)
+	if isDatedPatch(scheduledPatch) {
+		if startTime := getDatedStartTime(scheduledPatch); startTime.After(from) && !startTime.After(currentTime) {
+			return startTime, nil
+		}
+		return currentTime, nil
+	}
+	schedule, err := parseStartSchedule(scheduledPatch, nil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	startTime, err := getLatestScheduleTime(schedule, from, currentTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if startTime.IsZero() {
+		return currentTime, nil
+	}
+	return startTime, nil
+}
+
+// getRampStep returns the number of the steps of the ramp elapsed at the given time.
+func getRampStep(ramp *cronhpav1beta1.Ramp, startTime, currentTime time.Time) int64 {
+	elapsed := currentTime.Sub(startTime)
+	if elapsed < 0 {
+		return 0
+	}
+	if elapsed >= ramp.Duration.Duration || ramp.Duration.Duration <= 0 {
+		return int64(ramp.Steps)
+	}
+	return int64(elapsed) * int64(ramp.Steps) / int64(ramp.Duration.Duration)
+}
+
+// getNextRampTime returns the time of the next step of the active ramp, or the zero time if there is none.
+func (cronhpa *CronHorizontalPodAutoscaler) getNextRampTime(currentTime time.Time) time.Time {
+	ramp := cronhpa.Status.Ramp
+	if ramp == nil {
+		return time.Time{}
+	}
+	scheduledPatch := cronhpa.getScheduledPatch(ramp.PatchName)
+	if scheduledPatch == nil || scheduledPatch.Ramp == nil {
+		return time.Time{}
+	}
+	steps := int64(scheduledPatch.Ramp.Steps)
+	step := getRampStep(scheduledPatch.Ramp, ramp.StartTime.Time, currentTime)
+	if step >= steps {
+		return time.Time{}
+	}
+	// Round up not to come before the step.
+	return ramp.StartTime.Add(time.Duration((int64(scheduledPatch.Ramp.Duration.Duration)*(step+1) + steps - 1) / steps))
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	"github.com/dtaniwaki/cron-hpa/test"
+)
+
+func TestApplyRamp(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 2
+      maxReplicas: 20
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 10
+    ramp:
+      duration: 30m
+      steps: 4
+`
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, clientgoscheme.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+
+	startTime := time.Time{}
+	_ = startTime.UnmarshalText([]byte("2021-09-04T08:00:00+09:00"))
+
+	for name, c := range map[string]struct {
+		// The time when the patch is applied first.
+		firstTime   time.Duration
+		minReplicas map[time.Duration]int32
+	}{
+		"from the start": {0, map[time.Duration]int32{
+			0:                2,
+			8 * time.Minute:  4,
+			16 * time.Minute: 6,
+			23 * time.Minute: 8,
+			30 * time.Minute: 10,
+			40 * time.Minute: 10,
+		}},
+		"after a restart": {16 * time.Minute, map[time.Duration]int32{
+			16 * time.Minute: 6,
+			23 * time.Minute: 8,
+			30 * time.Minute: 10,
+		}},
+	} {
+		cronhpa := &CronHorizontalPodAutoscaler{}
+		err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cronhpa.ToCompatible()).Build()
+		reconciler := &CronHorizontalPodAutoscalerReconciler{
+			Client:   fakeClient,
+			Recorder: &test.FakeRecorder{},
+		}
+		err = cronhpa.CreateOrPatchHPA(ctx, "", startTime.Add(-time.Hour), reconciler)
+		if !assert.NoError(t, err, name) {
+			t.FailNow()
+		}
+
+		for _, elapsed := range []time.Duration{0, 8 * time.Minute, 16 * time.Minute, 23 * time.Minute, 30 * time.Minute, 40 * time.Minute} {
+			expected, ok := c.minReplicas[elapsed]
+			if !ok {
+				continue
+			}
+			currentTime := startTime.Add(elapsed)
+			err = cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime, reconciler)
+			if !assert.NoError(t, err, name) {
+				t.FailNow()
+			}
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			err = fakeClient.Get(ctx, cronhpa.ToHPANamespacedName(), hpa)
+			if !assert.NoError(t, err, name) {
+				t.FailNow()
+			}
+			if !assert.Equal(t, expected, *hpa.Spec.MinReplicas, name, elapsed) {
+				t.FailNow()
+			}
+			if !assert.True(t, startTime.Equal(cronhpa.Status.Ramp.StartTime.Time), name) {
+				t.FailNow()
+			}
+		}
+	}
+
+	// The next step.
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cronhpa.Status.Ramp = &cronhpav1beta1.RampStatus{PatchName: "daytime", FromMinReplicas: 2}
+	cronhpa.Status.Ramp.StartTime.Time = startTime
+	nextTime, err := cronhpa.GetNextReconcileTime(startTime.Add(8*time.Minute), nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, startTime.Add(15*time.Minute).Equal(nextTime), nextTime) {
+		t.FailNow()
+	}
+	nextTime, err = cronhpa.GetNextReconcileTime(startTime.Add(30*time.Minute), nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, nextTime.IsZero()) {
+		t.FailNow()
+	}
+
+	// Switch to a patch without a ramp.
+	hpa, err := cronhpa.NewHPA("")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = cronhpa.ApplyRamp("", startTime, hpa, hpa.DeepCopy())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, cronhpa.Status.Ramp) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+// isRelativeReplicas returns true if the replicas are relative to the base.
+func isRelativeReplicas(replicas *intstr.IntOrString) bool {
+	return replicas != nil && replicas.Type == intstr.String
+}
+
+// resolveReplicas resolves the replicas of a patch against the base replicas.
+// A percentage like `200%` multiplies the base rounding up, and a signed number like `+5` or `-2` offsets it.
+// The relative replicas are at least 1.
+func resolveReplicas(replicas intstr.IntOrString, base int32) (int32, error) {
+	if replicas.Type == intstr.Int {
+		return replicas.IntVal, nil
+	}
+	value := replicas.StrVal
+	var resolved int64
+	if strings.HasSuffix(value, "%") {
+		percent, err := strconv.ParseInt(strings.TrimSuffix(value, "%"), 10, 32)
+		if err != nil || percent < 0 {
+			return 0, fmt.Errorf("Invalid replicas %q: The percentage must be a non-negative integer", value)
+		}
+		resolved = (int64(base)*percent + 99) / 100
+	} else if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		offset, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid replicas %q: The offset must be an integer", value)
+		}
+		resolved = int64(base) + offset
+	} else {
+		return 0, fmt.Errorf("Invalid replicas %q: It must be an integer, a percentage like 200%% or an offset like +5", value)
+	}
+	if resolved < 1 {
+		return 1, nil
+	}
+	if resolved > math.MaxInt32 {
+		return math.MaxInt32, nil
+	}
+	return int32(resolved), nil
+}
+
+// applyReplicasPatch sets minReplicas and maxReplicas of the patch to the HPA, resolving the relative replicas
+// against the HPA before the patch. The relative minReplicas is kept at most maxReplicas, and the relative
+// maxReplicas is kept at least minReplicas.
+func applyReplicasPatch(patch *cronhpav1beta1.HPAPatch, hpa *autoscalingv2.HorizontalPodAutoscaler) error {
+	baseMinReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		baseMinReplicas = *hpa.Spec.MinReplicas
+	}
+	maxReplicas := hpa.Spec.MaxReplicas
+	if patch.MaxReplicas != nil {
+		resolved, err := resolveReplicas(*patch.MaxReplicas, hpa.Spec.MaxReplicas)
+		if err != nil {
+			return err
+		}
+		maxReplicas = resolved
+	}
+	if patch.MinReplicas != nil {
+		minReplicas, err := resolveReplicas(*patch.MinReplicas, baseMinReplicas)
+		if err != nil {
+			return err
+		}
+		if isRelativeReplicas(patch.MinReplicas) && minReplicas > maxReplicas {
+			minReplicas = maxReplicas
+		}
+		hpa.Spec.MinReplicas = &minReplicas
+	}
+	if isRelativeReplicas(patch.MaxReplicas) && hpa.Spec.MinReplicas != nil && maxReplicas < *hpa.Spec.MinReplicas {
+		maxReplicas = *hpa.Spec.MinReplicas
+	}
+	hpa.Spec.MaxReplicas = maxReplicas
+	return nil
+}
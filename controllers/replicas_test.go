@@ -0,0 +1,135 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+func TestResolveReplicas(t *testing.T) {
+	for _, c := range []struct {
+		replicas intstr.IntOrString
+		base     int32
+		expected int32
+		invalid  bool
+	}{
+		{intstr.FromInt(5), 3, 5, false},
+		{intstr.FromString("200%"), 3, 6, false},
+		{intstr.FromString("150%"), 3, 5, false},
+		{intstr.FromString("0%"), 3, 1, false},
+		{intstr.FromString("+5"), 3, 8, false},
+		{intstr.FromString("-5"), 3, 1, false},
+		{intstr.FromString("5"), 3, 0, true},
+		{intstr.FromString("-50%"), 3, 0, true},
+		{intstr.FromString("+x"), 3, 0, true},
+	} {
+		resolved, err := resolveReplicas(c.replicas, c.base)
+		if c.invalid {
+			if !assert.Error(t, err, c.replicas.String()) {
+				t.FailNow()
+			}
+			continue
+		}
+		if !assert.NoError(t, err, c.replicas.String()) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.expected, resolved, c.replicas.String()) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestNewHPAWithRelativeReplicas(t *testing.T) {
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 4
+      maxReplicas: 10
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: "200%"
+      maxReplicas: "+5"
+  - name: peak
+    schedule: "0 12 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: "400%"
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 8
+      maxReplicas: "50%"
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	for _, c := range []struct {
+		patchName   string
+		minReplicas int32
+		maxReplicas int32
+	}{
+		{"daytime", 8, 15},
+		// Clamped by maxReplicas.
+		{"peak", 10, 10},
+		// Kept at least minReplicas.
+		{"nighttime", 8, 8},
+	} {
+		hpa, err := cronhpa.NewHPA(c.patchName)
+		if !assert.NoError(t, err, c.patchName) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.minReplicas, *hpa.Spec.MinReplicas, c.patchName) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.maxReplicas, hpa.Spec.MaxReplicas, c.patchName) {
+			t.FailNow()
+		}
+	}
+
+	// Keep the meaning when the template changes.
+	minReplicas := int32(2)
+	cronhpa.Spec.Template.Spec.MinReplicas = &minReplicas
+	hpa, err := cronhpa.NewHPA("daytime")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(4), *hpa.Spec.MinReplicas) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,111 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// GetDesiredReplicas returns the replicas of the patch, or the replicas of the scale target without the patch.
+// It returns nil if the replicas should be kept as is.
+func (cronhpa *CronHorizontalPodAutoscaler) GetDesiredReplicas(patchName string) *int32 {
+	for _, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		if scheduledPatch.Name == patchName && scheduledPatch.Replicas != nil {
+			replicas := *scheduledPatch.Replicas
+			return &replicas
+		}
+	}
+	return cronhpa.Spec.ScaleTarget.Replicas
+}
+
+// UpdateScale updates the replicas of the scale target through the scale subresource.
+func (cronhpa *CronHorizontalPodAutoscaler) UpdateScale(ctx context.Context, patchName string, currentTime time.Time, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	logger := log.FromContext(ctx)
+
+	if reconciler.ScaleClient == nil {
+		return fmt.Errorf("No scale client to scale the target")
+	}
+	ref := cronhpa.Spec.ScaleTarget.ScaleTargetRef
+	gv, err := schema.ParseGroupVersion(ref.APIVersion)
+	if err != nil {
+		return err
+	}
+	mapping, err := reconciler.RESTMapper().RESTMapping(schema.GroupKind{Group: gv.Group, Kind: ref.Kind}, gv.Version)
+	if err != nil {
+		return err
+	}
+	resource := mapping.Resource.GroupResource()
+	scales := reconciler.ScaleClient.Scales(cronhpa.Namespace)
+	scale, err := scales.Get(ctx, resource, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	target := fmt.Sprintf("%s %s", ref.Kind, ref.Name)
+	skipped, err := cronhpa.isScaleTargetSkipped(ctx, gv.WithKind(ref.Kind), currentTime, reconciler)
+	if err != nil {
+		return err
+	}
+	if skipped {
+		logger.Info(fmt.Sprintf("Skip scaling %s by an annotation", target))
+		msg := fmt.Sprintf("Skipped scaling %s by an annotation", target)
+		return cronhpa.updateSyncStatus(ctx, patchName, currentTime, CronHPAEventSkipped, msg, metav1.ConditionFalse, conditionReasonSkipped, true, reconciler)
+	}
+
+	event := CronHPAEventSkipped
+	msg := fmt.Sprintf("Skipped scaling %s with no changes", target)
+	syncedReason := conditionReasonNoChanges
+	if replicas := cronhpa.GetDesiredReplicas(patchName); replicas != nil && scale.Spec.Replicas != *replicas {
+		currentReplicas := scale.Spec.Replicas
+		scale.Spec.Replicas = *replicas
+		if _, err := scales.Update(ctx, resource, scale, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		logger.Info(fmt.Sprintf("Scaled %s from %d to %d", target, currentReplicas, *replicas))
+		event = CronHPAEventUpdated
+		msg = fmt.Sprintf("Scaled %s from %d to %d replicas", target, currentReplicas, *replicas)
+		syncedReason = conditionReasonUpdated
+	}
+	return cronhpa.updateSyncStatus(ctx, patchName, currentTime, event, msg, metav1.ConditionTrue, syncedReason, false, reconciler)
+}
+
+// isScaleTargetSkipped returns true if scaling the target is skipped by the annotations on the workload like HPAs.
+// The annotations are ignored if the controller is not allowed to read the workload, whose kind can be any.
+func (cronhpa *CronHorizontalPodAutoscaler) isScaleTargetSkipped(ctx context.Context, gvk schema.GroupVersionKind, currentTime time.Time, reconciler *CronHorizontalPodAutoscalerReconciler) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	name := types.NamespacedName{Namespace: cronhpa.Namespace, Name: cronhpa.Spec.ScaleTarget.ScaleTargetRef.Name}
+	if err := reconciler.Get(ctx, name, obj); err != nil {
+		if errors.IsForbidden(err) {
+			logger.Info(fmt.Sprintf("Ignore the skip annotations on %s %s which the controller is not allowed to get", gvk.Kind, name.Name))
+			return false, nil
+		}
+		return false, err
+	}
+	return cronhpa.IsSkipped(ctx, obj, currentTime, reconciler)
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	scalefake "k8s.io/client-go/scale/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	"github.com/dtaniwaki/cron-hpa/test"
+)
+
+func TestUpdateScale(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  scaleTarget:
+    scaleTargetRef:
+      apiVersion: apps/v1
+      kind: Deployment
+      name: batch-consumer
+    replicas: 1
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    replicas: 5
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, appsv1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{appsv1.SchemeGroupVersion})
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+
+	replicas := int32(2)
+	updates := 0
+	scaleClient := &scalefake.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch-consumer", Namespace: "default"},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: replicas},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		scale := action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		replicas = scale.Spec.Replicas
+		updates++
+		return true, scale, nil
+	})
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:      fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(cronhpa.ToCompatible(), &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "batch-consumer", Namespace: "default"}}).Build(),
+		Recorder:    &test.FakeRecorder{},
+		ScaleClient: scaleClient,
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T08:00:00+09:00"))
+
+	for _, c := range []struct {
+		patchName string
+		replicas  int32
+		updates   int
+	}{
+		{"daytime", 5, 1},
+		{"daytime", 5, 1},
+		{"nighttime", 1, 2},
+		{"", 1, 2},
+	} {
+		err = cronhpa.UpdateScale(ctx, c.patchName, currentTime, reconciler)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.replicas, replicas, c.patchName) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.updates, updates, c.patchName) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, cronhpa.Status.LastScheduledPatchName) {
+			t.FailNow()
+		}
+	}
+	if !assert.True(t, meta.IsStatusConditionTrue(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)) {
+		t.FailNow()
+	}
+
+	// Keep the replicas without the default replicas.
+	cronhpa.Spec.ScaleTarget.Replicas = nil
+	if !assert.Nil(t, cronhpa.GetDesiredReplicas("nighttime")) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(5), *cronhpa.GetDesiredReplicas("daytime")) {
+		t.FailNow()
+	}
+}
+
+func TestUpdateScaleWithSkipAnnotation(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  scaleTarget:
+    scaleTargetRef:
+      apiVersion: apps/v1
+      kind: Deployment
+      name: batch-consumer
+    replicas: 1
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    replicas: 5
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "batch-consumer",
+			Namespace:   "default",
+			Annotations: map[string]string{annotationNameSkipUntil: "1h"},
+		},
+	}
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, appsv1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{appsv1.SchemeGroupVersion})
+	mapper.Add(appsv1.SchemeGroupVersion.WithKind("Deployment"), meta.RESTScopeNamespace)
+
+	replicas := int32(2)
+	scaleClient := &scalefake.FakeScaleClient{}
+	scaleClient.AddReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: "batch-consumer", Namespace: "default"},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: replicas},
+		}, nil
+	})
+	scaleClient.AddReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		scale := action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		replicas = scale.Spec.Replicas
+		return true, scale, nil
+	})
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithRESTMapper(mapper).WithObjects(cronhpa.ToCompatible(), deployment).Build()
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:      fakeClient,
+		Recorder:    &test.FakeRecorder{},
+		ScaleClient: scaleClient,
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T08:00:00+09:00"))
+
+	// Skipped until the annotation expires.
+	err = cronhpa.UpdateScale(ctx, "daytime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(2), replicas) {
+		t.FailNow()
+	}
+	untilTime := currentTime.Add(time.Hour)
+	if !assert.True(t, untilTime.Equal(cronhpa.Status.SkipUntil.Time), cronhpa.Status.SkipUntil) {
+		t.FailNow()
+	}
+	if !assert.True(t, meta.IsStatusConditionTrue(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeSuspended)) {
+		t.FailNow()
+	}
+	saved := &appsv1.Deployment{}
+	err = fakeClient.Get(ctx, client.ObjectKeyFromObject(deployment), saved)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, untilTime.Format(time.RFC3339), saved.Annotations[annotationNameSkipUntil]) {
+		t.FailNow()
+	}
+
+	// Scaled after the annotation expires.
+	cronhpa.Status.SkipUntil = nil
+	err = cronhpa.UpdateScale(ctx, "daytime", untilTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(5), replicas) {
+		t.FailNow()
+	}
+	if !assert.False(t, meta.IsStatusConditionTrue(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeSuspended)) {
+		t.FailNow()
+	}
+	err = fakeClient.Get(ctx, client.ObjectKeyFromObject(deployment), saved)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NotContains(t, saved.Annotations, annotationNameSkipUntil) {
+		t.FailNow()
+	}
+}
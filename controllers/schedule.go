@@ -0,0 +1,280 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const MAX_SCHEDULE_TRY = 1000000
+
+// MAX_SCHEDULE_LOOKBACK is the longest period to look back for the start of an active window.
+const MAX_SCHEDULE_LOOKBACK = 366 * 24 * time.Hour
+
+var standardParser = cron.NewParser(
+	cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
+)
+
+// cronSpec returns the cron spec of the schedule in the timezone.
+func cronSpec(schedule, timezone string) string {
+	if timezone == "" {
+		return schedule
+	}
+	return "CRON_TZ=" + timezone + " " + schedule
+}
+
+func parseSchedule(schedule, timezone string) (cron.Schedule, error) {
+	return standardParser.Parse(cronSpec(schedule, timezone))
+}
+
+func loadLocation(timezone string) (*time.Location, error) {
+	if timezone == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(timezone)
+}
+
+// exclusionSchedule is a schedule which skips the activation times on the excluded dates.
+type exclusionSchedule struct {
+	schedule cron.Schedule
+	calendar ExclusionCalendar
+	location *time.Location
+}
+
+func (s *exclusionSchedule) Next(t time.Time) time.Time {
+	for i := 0; i <= MAX_SCHEDULE_TRY; i++ {
+		t = s.schedule.Next(t)
+		if t.IsZero() || !s.calendar.Excludes(t.In(s.location)) {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// leadSchedule is a schedule which activates the lead time ahead of the underlying schedule.
+type leadSchedule struct {
+	schedule cron.Schedule
+	leadTime time.Duration
+}
+
+func (s *leadSchedule) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t.Add(s.leadTime))
+	if next.IsZero() {
+		return next
+	}
+	return next.Add(-s.leadTime)
+}
+
+// getLeadTime returns the lead time of the scheduled patch, or zero if it's not set.
+func getLeadTime(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch) time.Duration {
+	if scheduledPatch.LeadTime == nil {
+		return 0
+	}
+	return scheduledPatch.LeadTime.Duration
+}
+
+// getDatedStartTime returns the time when the dated patch is applied, which is the lead time ahead of StartTime.
+func getDatedStartTime(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch) time.Time {
+	return scheduledPatch.StartTime.Add(-getLeadTime(scheduledPatch))
+}
+
+// parseStartSchedule parses the schedule of the scheduled patch skipping the excluded dates.
+// The schedule activates the lead time ahead of the scheduled time, and the excluded dates are of the scheduled time.
+func parseStartSchedule(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, calendar ExclusionCalendar) (cron.Schedule, error) {
+	schedule, err := parseSchedule(scheduledPatch.Schedule, scheduledPatch.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	if len(calendar) > 0 {
+		location, err := loadLocation(scheduledPatch.Timezone)
+		if err != nil {
+			return nil, err
+		}
+		schedule = &exclusionSchedule{schedule: schedule, calendar: calendar, location: location}
+	}
+	if leadTime := getLeadTime(scheduledPatch); leadTime > 0 {
+		schedule = &leadSchedule{schedule: schedule, leadTime: leadTime}
+	}
+	return schedule, nil
+}
+
+// getLeadingScheduleTime returns the scheduled time of the patch which is applied ahead at the given time,
+// or the zero time if the scheduled time is not in the lead time.
+func getLeadingScheduleTime(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, currentTime time.Time) (time.Time, error) {
+	leadTime := getLeadTime(scheduledPatch)
+	if leadTime <= 0 {
+		return time.Time{}, nil
+	}
+	var scheduledTime time.Time
+	if isDatedPatch(scheduledPatch) {
+		scheduledTime = scheduledPatch.StartTime.Time
+	} else {
+		schedule, err := parseSchedule(scheduledPatch.Schedule, scheduledPatch.Timezone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		scheduledTime = schedule.Next(currentTime)
+	}
+	if !scheduledTime.After(currentTime) || scheduledTime.After(currentTime.Add(leadTime)) {
+		return time.Time{}, nil
+	}
+	return scheduledTime, nil
+}
+
+// getLatestScheduleTime returns the latest schedule time in (from, to], or the zero time if there is none.
+func getLatestScheduleTime(schedule cron.Schedule, from, to time.Time) (time.Time, error) {
+	nextTime := from
+	latestTime := time.Time{}
+	for i := 0; i <= MAX_SCHEDULE_TRY; i++ {
+		nextTime = schedule.Next(nextTime)
+		if nextTime.After(to) || nextTime.IsZero() {
+			return latestTime, nil
+		}
+		latestTime = nextTime
+	}
+	return time.Time{}, fmt.Errorf("Cannot find the next schedule")
+}
+
+// findLatestScheduleTime returns the latest schedule time until the given time.
+// It doubles the lookback period until it finds one or reaches MAX_SCHEDULE_LOOKBACK.
+func findLatestScheduleTime(schedule cron.Schedule, to time.Time) (time.Time, error) {
+	for lookback := time.Hour; ; lookback *= 2 {
+		if lookback > MAX_SCHEDULE_LOOKBACK {
+			lookback = MAX_SCHEDULE_LOOKBACK
+		}
+		latestTime, err := getLatestScheduleTime(schedule, to.Add(-lookback), to)
+		if err != nil || !latestTime.IsZero() || lookback == MAX_SCHEDULE_LOOKBACK {
+			return latestTime, err
+		}
+	}
+}
+
+// activePatch is a scheduled patch which is active at a time.
+type activePatch struct {
+	name     string
+	priority int32
+	time     time.Time
+}
+
+// overrides returns true if the patch takes precedence over the other by the priority and then by the recency.
+func (p *activePatch) overrides(other *activePatch) bool {
+	if other == nil {
+		return true
+	}
+	if p.priority != other.priority {
+		return p.priority > other.priority
+	}
+	return p.time.After(other.time)
+}
+
+// isDatedPatch returns true if the scheduled patch is applied only once from StartTime to EndTime.
+func isDatedPatch(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch) bool {
+	return scheduledPatch.StartTime != nil
+}
+
+// isWindowPatch returns true if the scheduled patch is active only in a window.
+func isWindowPatch(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch) bool {
+	return isDatedPatch(scheduledPatch) || scheduledPatch.Duration != nil || scheduledPatch.EndSchedule != ""
+}
+
+// isCompletedPatch returns true if the dated patch has expired at the given time.
+func isCompletedPatch(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, currentTime time.Time) bool {
+	return isDatedPatch(scheduledPatch) && scheduledPatch.EndTime != nil && !scheduledPatch.EndTime.After(currentTime)
+}
+
+// getLatestStartTime returns the start of the active window of the window patch, or the latest schedule time
+// of the other patch after the last cron timestamp. It returns the zero time if there is none.
+func getLatestStartTime(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, lastCronTimestamp *metav1.Time, currentTime time.Time, calendar ExclusionCalendar) (time.Time, error) {
+	if isWindowPatch(scheduledPatch) {
+		startTime, _, err := getActiveWindow(scheduledPatch, currentTime, calendar)
+		return startTime, err
+	}
+	if lastCronTimestamp == nil {
+		return time.Time{}, nil
+	}
+	schedule, err := parseStartSchedule(scheduledPatch, calendar)
+	if err != nil {
+		return time.Time{}, err
+	}
+	latestTime, err := getLatestScheduleTime(schedule, lastCronTimestamp.Time, currentTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Cannot find the next schedule of patch %s: %w", scheduledPatch.Name, err)
+	}
+	return latestTime, nil
+}
+
+// findLatestStartTime returns the start of the active window of the window patch, or the latest schedule time
+// of the other patch regardless of the last cron timestamp. It returns the zero time if there is none.
+func findLatestStartTime(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, currentTime time.Time, calendar ExclusionCalendar) (time.Time, error) {
+	if isWindowPatch(scheduledPatch) {
+		startTime, _, err := getActiveWindow(scheduledPatch, currentTime, calendar)
+		return startTime, err
+	}
+	schedule, err := parseStartSchedule(scheduledPatch, calendar)
+	if err != nil {
+		return time.Time{}, err
+	}
+	latestTime, err := findLatestScheduleTime(schedule, currentTime)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("Cannot find the latest schedule of patch %s: %w", scheduledPatch.Name, err)
+	}
+	return latestTime, nil
+}
+
+// getActiveWindow returns the start and the end of the window of the scheduled patch active at the given time.
+// It returns zero times if the window is not active.
+func getActiveWindow(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, currentTime time.Time, calendar ExclusionCalendar) (time.Time, time.Time, error) {
+	if isDatedPatch(scheduledPatch) {
+		startTime := getDatedStartTime(scheduledPatch)
+		if scheduledPatch.EndTime == nil || startTime.After(currentTime) || !scheduledPatch.EndTime.After(currentTime) {
+			return time.Time{}, time.Time{}, nil
+		}
+		return startTime, scheduledPatch.EndTime.Time, nil
+	}
+	schedule, err := parseStartSchedule(scheduledPatch, calendar)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	startTime, err := findLatestScheduleTime(schedule, currentTime)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("Failed to find the start of patch %s: %w", scheduledPatch.Name, err)
+	}
+	if startTime.IsZero() {
+		return time.Time{}, time.Time{}, nil
+	}
+	// The window ends by the scheduled time regardless of the lead time.
+	scheduledTime := startTime.Add(getLeadTime(scheduledPatch))
+	var endTime time.Time
+	if scheduledPatch.Duration != nil {
+		endTime = scheduledTime.Add(scheduledPatch.Duration.Duration)
+	} else {
+		endSchedule, err := parseSchedule(scheduledPatch.EndSchedule, scheduledPatch.Timezone)
+		if err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+		endTime = endSchedule.Next(scheduledTime)
+	}
+	if !endTime.After(currentTime) {
+		return time.Time{}, time.Time{}, nil
+	}
+	return startTime, endTime, nil
+}
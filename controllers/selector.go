@@ -0,0 +1,191 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+// ListSelectedHPAs returns the HPAs selected by HPASelector in the namespace sorted by name.
+// HPAs controlled by CronHPAs are excluded.
+func (cronhpa *CronHorizontalPodAutoscaler) ListSelectedHPAs(ctx context.Context, reconciler *CronHorizontalPodAutoscalerReconciler) ([]client.Object, error) {
+	selector, err := metav1.LabelSelectorAsSelector(cronhpa.Spec.HPASelector)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid HPA selector: %w", err)
+	}
+	list, err := NewVersionedHPAList(reconciler.GetHPAGroupVersion())
+	if err != nil {
+		return nil, err
+	}
+	if err := reconciler.List(ctx, list, client.InNamespace(cronhpa.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	objs := make([]client.Object, 0, len(items))
+	for _, item := range items {
+		obj, ok := item.(client.Object)
+		if !ok {
+			return nil, fmt.Errorf("Unexpected object %T", item)
+		}
+		if isControlledByCronHPA(obj) {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	sort.Slice(objs, func(i, j int) bool {
+		return objs[i].GetName() < objs[j].GetName()
+	})
+	return objs, nil
+}
+
+func isControlledByCronHPA(obj client.Object) bool {
+	owner := metav1.GetControllerOf(obj)
+	return owner != nil && owner.Kind == "CronHorizontalPodAutoscaler"
+}
+
+// PatchSelectedHPAs applies the patch to each HPA selected by HPASelector on top of its own baseline,
+// and restores the baselines of the HPAs which are not selected anymore.
+func (cronhpa *CronHorizontalPodAutoscaler) PatchSelectedHPAs(ctx context.Context, patchName string, currentTime time.Time, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	logger := log.FromContext(ctx)
+
+	gv := reconciler.GetHPAGroupVersion()
+	objs, err := cronhpa.ListSelectedHPAs(ctx, reconciler)
+	if err != nil {
+		return err
+	}
+
+	lastTargets := make(map[string]cronhpav1beta1.HPATargetStatus, len(cronhpa.Status.Targets))
+	for _, target := range cronhpa.Status.Targets {
+		lastTargets[target.Name] = target
+	}
+	targets := make([]cronhpav1beta1.HPATargetStatus, 0, len(objs))
+	updatedNames := make([]string, 0)
+	skippedNames := make([]string, 0)
+	for _, obj := range objs {
+		skipped, err := cronhpa.IsSkipped(ctx, obj, currentTime, reconciler)
+		if err != nil {
+			return err
+		}
+		hpa, err := FromVersionedHPA(obj)
+		if err != nil {
+			return err
+		}
+		target, ok := lastTargets[hpa.Name]
+		if ok {
+			delete(lastTargets, hpa.Name)
+		} else {
+			logger.Info(fmt.Sprintf("Selected HPA %s", hpa.Name))
+			target = cronhpav1beta1.HPATargetStatus{Name: hpa.Name}
+		}
+		if shouldCaptureBaseline(target.BaselineHPASpec, target.PatchName, hpa) {
+			target.BaselineHPASpec = hpa.Spec.DeepCopy()
+		}
+		target.Skipped = skipped
+		if target.Skipped {
+			logger.Info(fmt.Sprintf("Skip updating HPA %s by an annotation", hpa.Name))
+			skippedNames = append(skippedNames, hpa.Name)
+			targets = append(targets, target)
+			continue
+		}
+		newhpa, err := cronhpa.newAdoptedHPA(patchName, target.BaselineHPASpec, hpa)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(hpa.Spec, newhpa.Spec) {
+			newobj, err := ToVersionedHPA(newhpa, gv)
+			if err != nil {
+				return err
+			}
+			if err := reconciler.Patch(ctx, newobj, client.MergeFrom(obj)); err != nil {
+				return err
+			}
+			logger.Info(fmt.Sprintf("Updated HPA %s successfully", hpa.Name))
+			updatedNames = append(updatedNames, hpa.Name)
+		}
+		target.PatchName = patchName
+		targets = append(targets, target)
+	}
+
+	// Release the HPAs which are not selected anymore.
+	for name, target := range lastTargets {
+		logger.Info(fmt.Sprintf("Released HPA %s", name))
+		if err := cronhpa.restoreBaselineHPA(ctx, types.NamespacedName{Namespace: cronhpa.Namespace, Name: name}, target.BaselineHPASpec, reconciler); err != nil {
+			return err
+		}
+	}
+	cronhpa.Status.Targets = targets
+
+	event := CronHPAEventSkipped
+	msg := "Skipped updating HPAs with no changes"
+	syncedStatus, syncedReason := metav1.ConditionTrue, conditionReasonNoChanges
+	if len(updatedNames) > 0 {
+		event = CronHPAEventUpdated
+		msg = fmt.Sprintf("Updated HPAs %s", strings.Join(updatedNames, ","))
+		syncedReason = conditionReasonUpdated
+	}
+	suspended := false
+	if len(targets) == 0 {
+		msg = "Skipped updating HPAs with no selected HPAs"
+		syncedStatus, syncedReason = metav1.ConditionFalse, conditionReasonNoTargets
+	} else if len(skippedNames) > 0 {
+		msg = fmt.Sprintf("%s (skipped %s by an annotation)", msg, strings.Join(skippedNames, ","))
+		syncedStatus, syncedReason = metav1.ConditionFalse, conditionReasonSkipped
+		suspended = true
+	}
+	return cronhpa.updateSyncStatus(ctx, patchName, currentTime, event, msg, syncedStatus, syncedReason, suspended, reconciler)
+}
+
+// selects returns true if the CronHPA adopts the HPA by HPARef or HPASelector.
+func (cronhpa *CronHorizontalPodAutoscaler) selects(obj client.Object) bool {
+	if cronhpa.Spec.HPARef != nil {
+		return cronhpa.Spec.HPARef.Name == obj.GetName()
+	}
+	if cronhpa.Spec.HPASelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(cronhpa.Spec.HPASelector)
+		if err != nil {
+			return false
+		}
+		return selector.Matches(labels.Set(obj.GetLabels())) || containsTarget(cronhpa.Status.Targets, obj.GetName())
+	}
+	return false
+}
+
+func containsTarget(targets []cronhpav1beta1.HPATargetStatus, name string) bool {
+	for _, target := range targets {
+		if target.Name == name {
+			return true
+		}
+	}
+	return false
+}
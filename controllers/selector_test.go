@@ -0,0 +1,193 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	"github.com/dtaniwaki/cron-hpa/test"
+)
+
+func newSelectedHPA(t *testing.T, name string, labels map[string]string) *autoscalingv2.HorizontalPodAutoscaler {
+	hpaManifest := `
+apiVersion: autoscaling/v2
+kind: HorizontalPodAutoscaler
+metadata:
+  namespace: default
+spec:
+  scaleTargetRef:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: nginx
+  minReplicas: 1
+  maxReplicas: 10
+`
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	if err := yaml.Unmarshal([]byte(hpaManifest), hpa); err != nil {
+		t.Fatal(err)
+	}
+	hpa.Name = name
+	hpa.Labels = labels
+	return hpa
+}
+
+func TestPatchSelectedHPAs(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  hpaSelector:
+    matchLabels:
+      profile: web
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 5
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, clientgoscheme.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	owned := newSelectedHPA(t, "owned", map[string]string{"profile": "web"})
+	isController := true
+	owned.OwnerReferences = []metav1.OwnerReference{{APIVersion: cronhpav1beta1.GroupVersion.String(), Kind: "CronHorizontalPodAutoscaler", Name: "other", UID: "uid", Controller: &isController}}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		cronhpa.ToCompatible(),
+		newSelectedHPA(t, "web-1", map[string]string{"profile": "web"}),
+		newSelectedHPA(t, "web-2", map[string]string{"profile": "web"}),
+		newSelectedHPA(t, "batch", map[string]string{"profile": "batch"}),
+		owned,
+	).Build()
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:   fakeClient,
+		Recorder: &test.FakeRecorder{},
+	}
+	getMinReplicas := func(name string) int32 {
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		if err := fakeClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: name}, hpa); err != nil {
+			t.Fatal(err)
+		}
+		return *hpa.Spec.MinReplicas
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T08:00:00+09:00"))
+
+	// Apply the patch to the selected HPAs.
+	err = cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for name, minReplicas := range map[string]int32{"web-1": 5, "web-2": 5, "batch": 1, "owned": 1} {
+		if !assert.Equal(t, minReplicas, getMinReplicas(name), name) {
+			t.FailNow()
+		}
+	}
+	if !assert.Len(t, cronhpa.Status.Targets, 2) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "web-1", cronhpa.Status.Targets[0].Name) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "daytime", cronhpa.Status.Targets[0].PatchName) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(1), *cronhpa.Status.Targets[0].BaselineHPASpec.MinReplicas) {
+		t.FailNow()
+	}
+	if !assert.True(t, meta.IsStatusConditionTrue(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)) {
+		t.FailNow()
+	}
+
+	// Release an HPA which is not selected anymore.
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+	err = fakeClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web-2"}, hpa)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	hpa.Labels = map[string]string{"profile": "batch"}
+	err = fakeClient.Update(ctx, hpa)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, cronhpa.selects(hpa)) {
+		t.FailNow()
+	}
+	err = cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(1), getMinReplicas("web-2")) {
+		t.FailNow()
+	}
+	if !assert.Len(t, cronhpa.Status.Targets, 1) {
+		t.FailNow()
+	}
+	if !assert.False(t, cronhpa.selects(hpa)) {
+		t.FailNow()
+	}
+
+	// Restore the baselines.
+	err = cronhpa.CreateOrPatchHPA(ctx, "", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(1), getMinReplicas("web-1")) {
+		t.FailNow()
+	}
+
+	// No selected HPAs.
+	cronhpa.Spec.HPASelector.MatchLabels["profile"] = "none"
+	err = cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	condition := meta.FindStatusCondition(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeHPASynced)
+	if !assert.Equal(t, conditionReasonNoTargets, condition.Reason) {
+		t.FailNow()
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// parseSkipUntil returns the time when the skip-until annotation expires.
+// The value is an RFC 3339 timestamp, or a duration from the given time. It returns true if the value is a duration.
+func parseSkipUntil(value string, currentTime time.Time) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("Invalid %s annotation %q: It must be an RFC 3339 timestamp or a duration", annotationNameSkipUntil, value)
+	}
+	return currentTime.Add(d), true, nil
+}
+
+// IsSkipped returns true if updating the target object is skipped by the skip annotation or the skip-until annotation.
+// A skip-until annotation with a duration is replaced with the timestamp of its expiry, and the expired one is removed.
+// The earliest expiry is recorded in the status to reconcile the CronHPA again at the time.
+func (cronhpa *CronHorizontalPodAutoscaler) IsSkipped(ctx context.Context, obj client.Object, currentTime time.Time, reconciler *CronHorizontalPodAutoscalerReconciler) (bool, error) {
+	logger := log.FromContext(ctx)
+
+	annotations := obj.GetAnnotations()
+	if annotations[annotationNameSkip] == "true" {
+		return true, nil
+	}
+	value, ok := annotations[annotationNameSkipUntil]
+	if !ok {
+		return false, nil
+	}
+	until, isDuration, err := parseSkipUntil(value, currentTime)
+	if err != nil {
+		return false, err
+	}
+	if until.After(currentTime) {
+		if isDuration {
+			if err := patchAnnotation(ctx, obj, annotationNameSkipUntil, until.Format(time.RFC3339), reconciler); err != nil {
+				return false, err
+			}
+			logger.Info(fmt.Sprintf("Set the expiry of the skip on %s to %s", obj.GetName(), until.Format(time.RFC3339)))
+		}
+		if cronhpa.Status.SkipUntil == nil || until.Before(cronhpa.Status.SkipUntil.Time) {
+			cronhpa.Status.SkipUntil = &metav1.Time{Time: until}
+		}
+		return true, nil
+	}
+
+	if err := patchAnnotation(ctx, obj, annotationNameSkipUntil, "", reconciler); err != nil {
+		return false, err
+	}
+	logger.Info(fmt.Sprintf("The skip on %s expired", obj.GetName()))
+	msg := fmt.Sprintf("Resumed updating %s after the skip expired at %s", obj.GetName(), until.Format(time.RFC3339))
+	reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeNormal, CronHPAEventSkipExpired, msg)
+	return false, nil
+}
+
+// patchAnnotation sets the annotation on the object, or removes it if the value is empty.
+func patchAnnotation(ctx context.Context, obj client.Object, name, value string, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	base, ok := obj.DeepCopyObject().(client.Object)
+	if !ok {
+		return fmt.Errorf("Unexpected object %T", obj)
+	}
+	annotations := make(map[string]string, len(obj.GetAnnotations()))
+	for k, v := range obj.GetAnnotations() {
+		annotations[k] = v
+	}
+	if value == "" {
+		delete(annotations, name)
+	} else {
+		annotations[name] = value
+	}
+	obj.SetAnnotations(annotations)
+	return reconciler.Patch(ctx, obj, client.MergeFrom(base))
+}
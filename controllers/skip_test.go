@@ -0,0 +1,151 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+func TestSkipUntil(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  hpaRef:
+    name: nginx
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 5
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, clientgoscheme.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	hpa := newSelectedHPA(t, "nginx", nil)
+	hpa.Annotations = map[string]string{annotationNameSkipUntil: "1h"}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cronhpa.ToCompatible(), hpa).Build()
+	recorder := record.NewFakeRecorder(10)
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:   fakeClient,
+		Recorder: recorder,
+	}
+	getHPA := func() *autoscalingv2.HorizontalPodAutoscaler {
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+		if err := fakeClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "nginx"}, hpa); err != nil {
+			t.Fatal(err)
+		}
+		return hpa
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T08:00:00+09:00"))
+
+	// The duration is replaced with the timestamp of the expiry.
+	err = cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	until := currentTime.Add(time.Hour)
+	if !assert.Equal(t, until.Format(time.RFC3339), getHPA().Annotations[annotationNameSkipUntil]) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(1), *getHPA().Spec.MinReplicas) {
+		t.FailNow()
+	}
+	if !assert.True(t, until.Equal(cronhpa.Status.SkipUntil.Time)) {
+		t.FailNow()
+	}
+	if !assert.True(t, meta.IsStatusConditionTrue(cronhpa.Status.Conditions, cronhpav1beta1.ConditionTypeSuspended)) {
+		t.FailNow()
+	}
+	nextTime, err := cronhpa.GetNextReconcileTime(currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, until.Equal(nextTime)) {
+		t.FailNow()
+	}
+
+	// Still skipped before the expiry.
+	err = cronhpa.CreateOrPatchHPA(ctx, "daytime", currentTime.Add(30*time.Minute), reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(1), *getHPA().Spec.MinReplicas) {
+		t.FailNow()
+	}
+
+	// The annotation is removed and the patch is applied after the expiry.
+	for len(recorder.Events) > 0 {
+		<-recorder.Events
+	}
+	err = cronhpa.CreateOrPatchHPA(ctx, "daytime", until, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NotContains(t, getHPA().Annotations, annotationNameSkipUntil) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, int32(5), *getHPA().Spec.MinReplicas) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, cronhpa.Status.SkipUntil) {
+		t.FailNow()
+	}
+	if !assert.True(t, strings.HasPrefix(<-recorder.Events, "Normal SkipExpired")) {
+		t.FailNow()
+	}
+
+	// Invalid annotation.
+	_, _, err = parseSkipUntil("tomorrow", currentTime)
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+}
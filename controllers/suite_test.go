@@ -31,6 +31,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	cronhpav1alpha1 "github.com/dtaniwaki/cron-hpa/api/v1alpha1"
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -65,6 +66,8 @@ var _ = BeforeSuite(func() {
 
 	err = cronhpav1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
+	err = cronhpav1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
 
 	//+kubebuilder:scaffold:scheme
 
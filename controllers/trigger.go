@@ -0,0 +1,119 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+// parseTriggerDuration parses the value of the trigger-duration annotation. It returns zero if the value is empty.
+func parseTriggerDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("Invalid %s annotation %q: It must be a positive duration", annotationNameTriggerDuration, value)
+	}
+	return d, nil
+}
+
+// UpdateTriggeredPatch starts the scheduled patch named by the trigger annotation, and removes the trigger annotations.
+// The triggered patch is recorded in the status so that it's applied until it ends.
+func (cronhpa *CronHorizontalPodAutoscaler) UpdateTriggeredPatch(ctx context.Context, currentTime time.Time, reconciler *CronHorizontalPodAutoscalerReconciler) error {
+	logger := log.FromContext(ctx)
+
+	annotations := cronhpa.GetAnnotations()
+	patchName, ok := annotations[annotationNameTrigger]
+	if !ok {
+		return nil
+	}
+	duration, err := parseTriggerDuration(annotations[annotationNameTriggerDuration])
+	if err == nil && cronhpa.getScheduledPatch(patchName) == nil {
+		err = fmt.Errorf("No schedule patch named %s to trigger", patchName)
+	}
+	if err != nil {
+		logger.Error(err, "Failed to trigger a patch")
+		reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeWarning, CronHPAEventTriggerFailed, err.Error())
+	} else {
+		triggeredPatch := &cronhpav1beta1.TriggeredPatchStatus{Name: patchName, TriggeredTime: metav1.Time{Time: currentTime}}
+		msg := fmt.Sprintf("Triggered %s manually", patchName)
+		if duration > 0 {
+			triggeredPatch.EndTime = &metav1.Time{Time: currentTime.Add(duration)}
+			msg = fmt.Sprintf("%s for %s", msg, duration)
+		}
+		cronhpa.Status.TriggeredPatch = triggeredPatch
+		// Save the status first not to lose the trigger when removing the annotations.
+		if err := reconciler.Status().Update(ctx, cronhpa.ToCompatible()); err != nil {
+			return err
+		}
+		logger.Info(msg)
+		reconciler.Recorder.Event(cronhpa.ToCompatible(), corev1.EventTypeNormal, CronHPAEventTriggered, msg)
+	}
+
+	base := cronhpa.ToCompatible().DeepCopy()
+	delete(annotations, annotationNameTrigger)
+	delete(annotations, annotationNameTriggerDuration)
+	cronhpa.SetAnnotations(annotations)
+	return reconciler.Patch(ctx, cronhpa.ToCompatible(), client.MergeFrom(base))
+}
+
+// isTriggerEnded returns true if the triggered patch has an end and it has passed at the given time.
+func (cronhpa *CronHorizontalPodAutoscaler) isTriggerEnded(currentTime time.Time) bool {
+	triggeredPatch := cronhpa.Status.TriggeredPatch
+	return triggeredPatch != nil && triggeredPatch.EndTime != nil && !currentTime.Before(triggeredPatch.EndTime.Time)
+}
+
+// getTriggeredPatch returns the triggered patch if it's still active against the scheduled current patch.
+// A triggered patch with EndTime is active until the time, and the other is active until another patch starts.
+// The ended triggered patch is cleared from the status.
+func (cronhpa *CronHorizontalPodAutoscaler) getTriggeredPatch(ctx context.Context, currentPatch *activePatch, currentTime time.Time) *activePatch {
+	logger := log.FromContext(ctx)
+
+	triggeredPatch := cronhpa.Status.TriggeredPatch
+	if triggeredPatch == nil {
+		return nil
+	}
+	active := false
+	priority := int32(math.MinInt32)
+	if cronhpa.getScheduledPatch(triggeredPatch.Name) == nil {
+		logger.Info(fmt.Sprintf("Lost triggered patch %s", triggeredPatch.Name))
+	} else if triggeredPatch.EndTime != nil {
+		// Patches firing before the end never override the triggered patch.
+		active = currentTime.Before(triggeredPatch.EndTime.Time)
+		priority = math.MaxInt32
+	} else {
+		// Patches firing after the trigger override the triggered patch.
+		active = currentPatch == nil || !currentPatch.time.After(triggeredPatch.TriggeredTime.Time)
+	}
+	if !active {
+		logger.Info(fmt.Sprintf("Triggered patch %s ended", triggeredPatch.Name))
+		cronhpa.Status.TriggeredPatch = nil
+		return nil
+	}
+	return &activePatch{name: triggeredPatch.Name, priority: priority, time: triggeredPatch.TriggeredTime.Time}
+}
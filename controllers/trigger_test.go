@@ -0,0 +1,233 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	"github.com/dtaniwaki/cron-hpa/test"
+)
+
+const triggerCronHPAManifest = `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+  - name: peak
+    schedule: "0 12 1 1 *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 8
+`
+
+func TestUpdateTriggeredPatch(t *testing.T) {
+	ctx := context.TODO()
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(triggerCronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cronhpa.Annotations = map[string]string{
+		annotationNameTrigger:         "peak",
+		annotationNameTriggerDuration: "1h",
+	}
+
+	scheme := runtime.NewScheme()
+	if !assert.NoError(t, cronhpav1beta1.AddToScheme(scheme)) {
+		t.FailNow()
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cronhpa.ToCompatible()).Build()
+	reconciler := &CronHorizontalPodAutoscalerReconciler{
+		Client:   fakeClient,
+		Recorder: &test.FakeRecorder{},
+	}
+
+	currentTime := time.Time{}
+	_ = currentTime.UnmarshalText([]byte("2021-09-04T09:00:00+09:00"))
+
+	err = cronhpa.UpdateTriggeredPatch(ctx, currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	saved := &cronhpav1beta1.CronHorizontalPodAutoscaler{}
+	err = fakeClient.Get(ctx, cronhpa.ToNamespacedName(), saved)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NotContains(t, saved.Annotations, annotationNameTrigger) {
+		t.FailNow()
+	}
+	if !assert.NotContains(t, saved.Annotations, annotationNameTriggerDuration) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "peak", saved.Status.TriggeredPatch.Name) {
+		t.FailNow()
+	}
+	if !assert.True(t, currentTime.Add(time.Hour).Equal(saved.Status.TriggeredPatch.EndTime.Time)) {
+		t.FailNow()
+	}
+	nextTime, err := cronhpa.GetNextReconcileTime(currentTime, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, currentTime.Add(time.Hour).Equal(nextTime)) {
+		t.FailNow()
+	}
+
+	// Unknown patch.
+	cronhpa.Annotations = map[string]string{annotationNameTrigger: "unknown"}
+	cronhpa.Status.TriggeredPatch = nil
+	err = fakeClient.Update(ctx, cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = cronhpa.UpdateTriggeredPatch(ctx, currentTime, reconciler)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.NotContains(t, cronhpa.Annotations, annotationNameTrigger) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, cronhpa.Status.TriggeredPatch) {
+		t.FailNow()
+	}
+}
+
+func TestGetCurrentPatchNameWithTriggeredPatch(t *testing.T) {
+	ctx := context.TODO()
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(triggerCronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	triggeredTime := time.Time{}
+	_ = triggeredTime.UnmarshalText([]byte("2021-09-04T21:00:00+09:00"))
+	cronhpa.Status.LastCronTimestamp = &metav1.Time{Time: triggeredTime.Add(-time.Hour)}
+	cronhpa.Status.LastScheduledPatchName = "daytime"
+
+	// Active until the end even if another patch fires.
+	endTime := triggeredTime.Add(2 * time.Hour)
+	for _, c := range []struct {
+		currentTime time.Time
+		patchName   string
+	}{
+		{triggeredTime, "peak"},
+		{triggeredTime.Add(90 * time.Minute), "peak"},
+		{endTime, "nighttime"},
+	} {
+		cronhpa.Status.TriggeredPatch = &cronhpav1beta1.TriggeredPatchStatus{Name: "peak", TriggeredTime: metav1.Time{Time: triggeredTime}, EndTime: &metav1.Time{Time: endTime}}
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, c.currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+		patchName, err = cronhpa.GetFiredPatchName(ctx, "nighttime", c.currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+	}
+	if !assert.Nil(t, cronhpa.Status.TriggeredPatch) {
+		t.FailNow()
+	}
+
+	// Active until another patch fires without the end.
+	for _, c := range []struct {
+		currentTime time.Time
+		patchName   string
+	}{
+		{triggeredTime, "peak"},
+		{triggeredTime.Add(30 * time.Minute), "peak"},
+		{triggeredTime.Add(90 * time.Minute), "nighttime"},
+	} {
+		cronhpa.Status.TriggeredPatch = &cronhpav1beta1.TriggeredPatchStatus{Name: "peak", TriggeredTime: metav1.Time{Time: triggeredTime}}
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, c.currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+	}
+	if !assert.Nil(t, cronhpa.Status.TriggeredPatch) {
+		t.FailNow()
+	}
+	cronhpa.Status.TriggeredPatch = &cronhpav1beta1.TriggeredPatchStatus{Name: "peak", TriggeredTime: metav1.Time{Time: triggeredTime}}
+	patchName, err := cronhpa.GetFiredPatchName(ctx, "nighttime", triggeredTime.Add(30*time.Minute), nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "nighttime", patchName) {
+		t.FailNow()
+	}
+
+	// Back to the schedule after the end once the triggered patch was applied.
+	_ = triggeredTime.UnmarshalText([]byte("2021-09-04T09:00:00+09:00"))
+	endTime = triggeredTime.Add(time.Hour)
+	for _, c := range []struct {
+		currentTime time.Time
+		patchName   string
+	}{
+		{triggeredTime.Add(30 * time.Minute), "peak"},
+		{endTime, "daytime"},
+		{endTime.Add(time.Hour), "daytime"},
+	} {
+		cronhpa.Status.LastCronTimestamp = &metav1.Time{Time: triggeredTime}
+		cronhpa.Status.LastScheduledPatchName = "peak"
+		cronhpa.Status.TriggeredPatch = &cronhpav1beta1.TriggeredPatchStatus{Name: "peak", TriggeredTime: metav1.Time{Time: triggeredTime}, EndTime: &metav1.Time{Time: endTime}}
+		patchName, err := cronhpa.GetCurrentPatchName(ctx, c.currentTime, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+		if !assert.Equal(t, c.patchName, patchName, c.currentTime) {
+			t.FailNow()
+		}
+	}
+}
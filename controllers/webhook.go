@@ -0,0 +1,428 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+)
+
+// CronHorizontalPodAutoscalerWebhook defaults and validates CronHorizontalPodAutoscaler objects and converts their versions.
+type CronHorizontalPodAutoscalerWebhook struct {
+	// DefaultTimezone is the timezone of the scheduled patches without timezones in the cluster.
+	DefaultTimezone string
+}
+
+//+kubebuilder:webhook:path=/mutate-cron-hpa-dtaniwaki-github-com-v1beta1-cronhorizontalpodautoscaler,mutating=true,failurePolicy=fail,sideEffects=None,groups=cron-hpa.dtaniwaki.github.com,resources=cronhorizontalpodautoscalers,verbs=create;update,versions=v1beta1,name=mcronhorizontalpodautoscaler.kb.io,admissionReviewVersions=v1
+//+kubebuilder:webhook:path=/validate-cron-hpa-dtaniwaki-github-com-v1beta1-cronhorizontalpodautoscaler,mutating=false,failurePolicy=fail,sideEffects=None,groups=cron-hpa.dtaniwaki.github.com,resources=cronhorizontalpodautoscalers,verbs=create;update,versions=v1beta1,name=vcronhorizontalpodautoscaler.kb.io,admissionReviewVersions=v1
+
+// SetupWebhookWithManager sets up the webhooks with the Manager.
+func (w *CronHorizontalPodAutoscalerWebhook) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&cronhpav1beta1.CronHorizontalPodAutoscaler{}).
+		WithDefaulter(w).
+		WithValidator(w).
+		Complete()
+}
+
+// Default fills in the effective timezones of the scheduled patches.
+func (w *CronHorizontalPodAutoscalerWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	compatible, ok := obj.(*cronhpav1beta1.CronHorizontalPodAutoscaler)
+	if !ok {
+		return fmt.Errorf("Unexpected object %T", obj)
+	}
+	(*CronHorizontalPodAutoscaler)(compatible).SetDefaultTimezones(w.DefaultTimezone)
+	return nil
+}
+
+// ValidateCreate validates a created CronHorizontalPodAutoscaler.
+func (w *CronHorizontalPodAutoscalerWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	return w.validate(ctx, obj)
+}
+
+// ValidateUpdate validates an updated CronHorizontalPodAutoscaler.
+// The spec is validated only when it changes so that the objects created before the validation, or invalid under
+// newer rules, can still be annotated, get finalizers and be deleted.
+func (w *CronHorizontalPodAutoscalerWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	oldCompatible, ok := oldObj.(*cronhpav1beta1.CronHorizontalPodAutoscaler)
+	if !ok {
+		return fmt.Errorf("Unexpected object %T", oldObj)
+	}
+	compatible, ok := newObj.(*cronhpav1beta1.CronHorizontalPodAutoscaler)
+	if !ok {
+		return fmt.Errorf("Unexpected object %T", newObj)
+	}
+	if compatible.DeletionTimestamp != nil {
+		return nil
+	}
+	if equality.Semantic.DeepEqual(oldCompatible.Spec, compatible.Spec) {
+		cronhpa := (*CronHorizontalPodAutoscaler)(compatible)
+		if errs := validateTrigger(cronhpa, field.NewPath("metadata", "annotations")); len(errs) > 0 {
+			return apierrors.NewInvalid(cronhpav1beta1.GroupVersion.WithKind("CronHorizontalPodAutoscaler").GroupKind(), cronhpa.Name, errs)
+		}
+		return nil
+	}
+	return w.validate(ctx, newObj)
+}
+
+// ValidateDelete validates a deleted CronHorizontalPodAutoscaler.
+func (w *CronHorizontalPodAutoscalerWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func (w *CronHorizontalPodAutoscalerWebhook) validate(ctx context.Context, obj runtime.Object) error {
+	compatible, ok := obj.(*cronhpav1beta1.CronHorizontalPodAutoscaler)
+	if !ok {
+		return fmt.Errorf("Unexpected object %T", obj)
+	}
+	cronhpa := (*CronHorizontalPodAutoscaler)(compatible)
+
+	errs := field.ErrorList{}
+	if _, err := loadLocation(cronhpa.Spec.Timezone); err != nil {
+		errs = append(errs, field.Invalid(field.NewPath("spec", "timezone"), cronhpa.Spec.Timezone, err.Error()))
+	}
+	// The patched HPAs are validated only with the template because the baselines of the adopted HPAs are unknown here.
+	hasTemplate := cronhpa.Spec.Template != nil
+	targetFields := make([]string, 0)
+	if hasTemplate {
+		targetFields = append(targetFields, "template")
+	}
+	if cronhpa.Spec.HPARef != nil {
+		targetFields = append(targetFields, "hpaRef")
+		if cronhpa.Spec.HPARef.Name == "" {
+			errs = append(errs, field.Required(field.NewPath("spec", "hpaRef", "name"), "Must be set"))
+		}
+	}
+	if cronhpa.Spec.HPASelector != nil {
+		targetFields = append(targetFields, "hpaSelector")
+		if _, err := metav1.LabelSelectorAsSelector(cronhpa.Spec.HPASelector); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "hpaSelector"), cronhpa.Spec.HPASelector.String(), err.Error()))
+		}
+	}
+	if cronhpa.Spec.ScaleTarget != nil {
+		targetFields = append(targetFields, "scaleTarget")
+		refPath := field.NewPath("spec", "scaleTarget", "scaleTargetRef")
+		if cronhpa.Spec.ScaleTarget.ScaleTargetRef.Kind == "" {
+			errs = append(errs, field.Required(refPath.Child("kind"), "Must be set"))
+		}
+		if cronhpa.Spec.ScaleTarget.ScaleTargetRef.Name == "" {
+			errs = append(errs, field.Required(refPath.Child("name"), "Must be set"))
+		}
+	}
+	if cronhpa.Spec.ScaledObjectRef != nil {
+		targetFields = append(targetFields, "scaledObjectRef")
+		if cronhpa.Spec.ScaledObjectRef.Name == "" {
+			errs = append(errs, field.Required(field.NewPath("spec", "scaledObjectRef", "name"), "Must be set"))
+		}
+	}
+	if len(targetFields) == 0 {
+		errs = append(errs, field.Required(field.NewPath("spec", "template"), "One of template, hpaRef, hpaSelector, scaleTarget and scaledObjectRef must be set"))
+	} else if len(targetFields) > 1 {
+		errs = append(errs, field.Forbidden(field.NewPath("spec"), fmt.Sprintf("Only one of template, hpaRef, hpaSelector, scaleTarget and scaledObjectRef can be set, but %s are set", strings.Join(targetFields, ", "))))
+	}
+	// The patches of the HPAs are used with the template, hpaRef and hpaSelector.
+	patchTargetField := ""
+	if len(targetFields) == 1 && (targetFields[0] == "scaleTarget" || targetFields[0] == "scaledObjectRef") {
+		patchTargetField = targetFields[0]
+	}
+	if hasTemplate {
+		if hpa, err := cronhpa.NewHPA(""); err != nil {
+			errs = append(errs, field.Invalid(field.NewPath("spec", "template"), "", err.Error()))
+		} else {
+			errs = append(errs, validateHPASpec(&hpa.Spec, field.NewPath("spec", "template", "spec"))...)
+		}
+	}
+	scheduledPatchesPath := field.NewPath("spec", "scheduledPatches")
+	patchNames := map[string]bool{}
+	for i, scheduledPatch := range cronhpa.Spec.ScheduledPatches {
+		fldPath := scheduledPatchesPath.Index(i)
+		if patchNames[scheduledPatch.Name] {
+			errs = append(errs, field.Duplicate(fldPath.Child("name"), scheduledPatch.Name))
+		}
+		patchNames[scheduledPatch.Name] = true
+		if hasTemplate {
+			if hpa, err := cronhpa.NewHPA(scheduledPatch.Name); err != nil {
+				errs = append(errs, field.Invalid(fldPath, scheduledPatch.Name, err.Error()))
+			} else {
+				errs = append(errs, validateHPASpec(&hpa.Spec, fldPath)...)
+			}
+		} else if patchTargetField == "" {
+			errs = append(errs, validateGenericHPAPatches(&scheduledPatch, fldPath)...)
+		}
+		errs = append(errs, validatePatchTarget(&scheduledPatch, patchTargetField, fldPath)...)
+		if scheduledPatch.Patch != nil {
+			errs = append(errs, validateReplicasPatch(scheduledPatch.Patch, fldPath.Child("patch"))...)
+			errs = append(errs, validateMetricsPatch(scheduledPatch.Patch, fldPath.Child("patch"))...)
+		}
+		if scheduledPatch.Ramp != nil {
+			errs = append(errs, validateRamp(scheduledPatch.Ramp, hasTemplate || cronhpa.Spec.HPARef != nil, fldPath.Child("ramp"))...)
+		}
+		errs = append(errs, validateSchedule(&scheduledPatch, fldPath)...)
+	}
+	errs = append(errs, validateExclusionCalendar(cronhpa.Spec.ExclusionCalendar, field.NewPath("spec", "exclusionCalendar"))...)
+	errs = append(errs, validateScaleDownGuard(cronhpa.Spec.ScaleDownGuard, hasTemplate || cronhpa.Spec.HPARef != nil, field.NewPath("spec", "scaleDownGuard"))...)
+	errs = append(errs, validateTrigger(cronhpa, field.NewPath("metadata", "annotations"))...)
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(cronhpav1beta1.GroupVersion.WithKind("CronHorizontalPodAutoscaler").GroupKind(), cronhpa.Name, errs)
+	}
+	return nil
+}
+
+// validateHPASpec validates the HPA spec generated by the template and the patch.
+func validateHPASpec(spec *autoscalingv2.HorizontalPodAutoscalerSpec, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if spec.ScaleTargetRef.Kind == "" {
+		errs = append(errs, field.Required(fldPath.Child("scaleTargetRef", "kind"), "Must be set in the HPA"))
+	}
+	if spec.ScaleTargetRef.Name == "" {
+		errs = append(errs, field.Required(fldPath.Child("scaleTargetRef", "name"), "Must be set in the HPA"))
+	}
+	if spec.MaxReplicas < 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("maxReplicas"), spec.MaxReplicas, "Must be greater than or equal to 1 in the HPA"))
+	}
+	if spec.MinReplicas != nil && *spec.MinReplicas > spec.MaxReplicas {
+		errs = append(errs, field.Invalid(fldPath.Child("minReplicas"), *spec.MinReplicas, fmt.Sprintf("Must be less than or equal to maxReplicas %d in the HPA", spec.MaxReplicas)))
+	}
+	return errs
+}
+
+// validateGenericHPAPatches validates the generic patches of the adopted HPAs, whose baselines are unknown here,
+// by applying them to a placeholder HPA. The JSON patch operations are decoded but not applied
+// because they can refer to the fields which only the baselines have.
+func validateGenericHPAPatches(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if scheduledPatch.StrategicMergePatch == nil && scheduledPatch.JSONPatch == nil {
+		return errs
+	}
+	if scheduledPatch.JSONPatch != nil {
+		if _, err := decodeGenericHPAJSONPatch(scheduledPatch.JSONPatch); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("jsonPatch"), "", err.Error()))
+		}
+	}
+	patch := scheduledPatch.DeepCopy()
+	patch.JSONPatch = nil
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "placeholder"},
+			MaxReplicas:    math.MaxInt32,
+		},
+	}
+	if err := applyGenericHPAPatches(patch, hpa); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("strategicMergePatch"), "", err.Error()))
+	} else {
+		errs = append(errs, validateHPASpec(&hpa.Spec, fldPath)...)
+	}
+	return errs
+}
+
+// validatePatchTarget validates that the scheduled patch has only the patches of the target field,
+// which is empty for HPAs.
+func validatePatchTarget(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, targetField string, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	for _, patchField := range []struct {
+		name        string
+		set         bool
+		targetField string
+	}{
+		{"patch", scheduledPatch.Patch != nil, ""},
+		{"strategicMergePatch", scheduledPatch.StrategicMergePatch != nil, ""},
+		{"jsonPatch", scheduledPatch.JSONPatch != nil, ""},
+		{"replicas", scheduledPatch.Replicas != nil, "scaleTarget"},
+		{"scaledObjectPatch", scheduledPatch.ScaledObjectPatch != nil, "scaledObjectRef"},
+	} {
+		if !patchField.set || patchField.targetField == targetField {
+			continue
+		}
+		if patchField.targetField == "" {
+			errs = append(errs, field.Forbidden(fldPath.Child(patchField.name), fmt.Sprintf("Cannot be set with %s", targetField)))
+		} else {
+			errs = append(errs, field.Forbidden(fldPath.Child(patchField.name), fmt.Sprintf("Cannot be set without %s", patchField.targetField)))
+		}
+	}
+	if scheduledPatch.ScaledObjectPatch != nil {
+		for i, trigger := range scheduledPatch.ScaledObjectPatch.Triggers {
+			if trigger.Name == "" && trigger.Type == "" {
+				errs = append(errs, field.Required(fldPath.Child("scaledObjectPatch", "triggers").Index(i), "Either name or type must be set"))
+			}
+		}
+	}
+	return errs
+}
+
+// validateRamp validates the ramp of minReplicas, which is available only for a single HPA.
+func validateRamp(ramp *cronhpav1beta1.Ramp, singleHPA bool, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if !singleHPA {
+		errs = append(errs, field.Forbidden(fldPath, "Can be set only with template or hpaRef"))
+	}
+	if ramp.Duration.Duration <= 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("duration"), ramp.Duration.String(), "Must be positive"))
+	}
+	if ramp.Steps < 1 {
+		errs = append(errs, field.Invalid(fldPath.Child("steps"), ramp.Steps, "Must be at least 1"))
+	}
+	return errs
+}
+
+// validateReplicasPatch validates the format of the relative replicas, which are resolved against the HPA later.
+func validateReplicasPatch(patch *cronhpav1beta1.HPAPatch, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	for _, replicasField := range []struct {
+		name     string
+		replicas *intstr.IntOrString
+	}{
+		{"minReplicas", patch.MinReplicas},
+		{"maxReplicas", patch.MaxReplicas},
+	} {
+		if replicasField.replicas == nil {
+			continue
+		}
+		if _, err := resolveReplicas(*replicasField.replicas, 1); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child(replicasField.name), replicasField.replicas.String(), err.Error()))
+		}
+	}
+	return errs
+}
+
+// validateMetricsPatch validates the metrics to remove, which are available only in the Merge mode.
+func validateMetricsPatch(patch *cronhpav1beta1.HPAPatch, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if len(patch.RemoveMetrics) > 0 && patch.MetricsMergeMode != cronhpav1beta1.MetricsMergeModeMerge {
+		errs = append(errs, field.Forbidden(fldPath.Child("removeMetrics"), "Can be set only with the Merge mode"))
+	}
+	for i, identity := range patch.RemoveMetrics {
+		if identity.Name == "" {
+			errs = append(errs, field.Required(fldPath.Child("removeMetrics").Index(i).Child("name"), "Must be set"))
+		}
+	}
+	return errs
+}
+
+// validateScaleDownGuard validates the scale-down guard, which is available only for a single HPA.
+func validateScaleDownGuard(guard *cronhpav1beta1.ScaleDownGuard, singleHPA bool, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if guard == nil {
+		return errs
+	}
+	if !singleHPA {
+		errs = append(errs, field.Forbidden(fldPath, "Can be set only with template or hpaRef"))
+	}
+	if guard.RetryInterval != nil && guard.RetryInterval.Duration <= 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("retryInterval"), guard.RetryInterval.Duration.String(), "Must be positive"))
+	}
+	return errs
+}
+
+// validateSchedule validates the schedule, the timezone, the lead time, the starting deadline and the window of the scheduled patch
+// with the same parser as the controller.
+func validateSchedule(scheduledPatch *cronhpav1beta1.CronHorizontalPodAutoscalerScheduledPatch, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if _, err := loadLocation(scheduledPatch.Timezone); err != nil {
+		return append(errs, field.Invalid(fldPath.Child("timezone"), scheduledPatch.Timezone, err.Error()))
+	}
+	if scheduledPatch.LeadTime != nil && scheduledPatch.LeadTime.Duration < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("leadTime"), scheduledPatch.LeadTime.Duration.String(), "Must not be negative"))
+	}
+	if scheduledPatch.StartingDeadlineSeconds != nil && *scheduledPatch.StartingDeadlineSeconds < 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("startingDeadlineSeconds"), *scheduledPatch.StartingDeadlineSeconds, "Must not be negative"))
+	}
+	if scheduledPatch.MissedSchedulePolicy != "" && scheduledPatch.StartingDeadlineSeconds == nil {
+		errs = append(errs, field.Forbidden(fldPath.Child("missedSchedulePolicy"), "Can be set only with startingDeadlineSeconds"))
+	}
+	if isDatedPatch(scheduledPatch) {
+		if scheduledPatch.Schedule != "" {
+			errs = append(errs, field.Forbidden(fldPath.Child("schedule"), "Cannot be set with startTime"))
+		}
+		if scheduledPatch.Duration != nil {
+			errs = append(errs, field.Forbidden(fldPath.Child("duration"), "Cannot be set with startTime"))
+		}
+		if scheduledPatch.EndSchedule != "" {
+			errs = append(errs, field.Forbidden(fldPath.Child("endSchedule"), "Cannot be set with startTime"))
+		}
+		if scheduledPatch.EndTime == nil {
+			errs = append(errs, field.Required(fldPath.Child("endTime"), "Must be set with startTime"))
+		} else if !scheduledPatch.EndTime.After(scheduledPatch.StartTime.Time) {
+			errs = append(errs, field.Invalid(fldPath.Child("endTime"), scheduledPatch.EndTime.String(), "Must be after startTime"))
+		}
+		return errs
+	}
+	if scheduledPatch.EndTime != nil {
+		errs = append(errs, field.Forbidden(fldPath.Child("endTime"), "Cannot be set without startTime"))
+	}
+	if scheduledPatch.Schedule == "" {
+		errs = append(errs, field.Required(fldPath.Child("schedule"), "Must be set without startTime"))
+	} else if _, err := parseSchedule(scheduledPatch.Schedule, scheduledPatch.Timezone); err != nil {
+		errs = append(errs, field.Invalid(fldPath.Child("schedule"), scheduledPatch.Schedule, err.Error()))
+	}
+	if scheduledPatch.Duration != nil && scheduledPatch.EndSchedule != "" {
+		errs = append(errs, field.Forbidden(fldPath.Child("endSchedule"), "Cannot be set with duration"))
+	}
+	if scheduledPatch.Duration != nil && scheduledPatch.Duration.Duration <= 0 {
+		errs = append(errs, field.Invalid(fldPath.Child("duration"), scheduledPatch.Duration.Duration.String(), "Must be positive"))
+	}
+	if scheduledPatch.EndSchedule != "" {
+		if _, err := parseSchedule(scheduledPatch.EndSchedule, scheduledPatch.Timezone); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("endSchedule"), scheduledPatch.EndSchedule, err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateExclusionCalendar(calendar *cronhpav1beta1.ExclusionCalendar, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if calendar == nil {
+		return errs
+	}
+	for i, date := range calendar.Dates {
+		if _, err := time.Parse(dateFormat, date); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Child("dates").Index(i), date, "Must be in the format of YYYY-MM-DD"))
+		}
+	}
+	if calendar.ConfigMapRef != nil && calendar.ConfigMapRef.Name == "" {
+		errs = append(errs, field.Required(fldPath.Child("configMapRef", "name"), "Must be set"))
+	}
+	return errs
+}
+
+// validateTrigger validates the annotations to trigger a patch manually.
+func validateTrigger(cronhpa *CronHorizontalPodAutoscaler, fldPath *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	annotations := cronhpa.GetAnnotations()
+	if patchName, ok := annotations[annotationNameTrigger]; ok && cronhpa.getScheduledPatch(patchName) == nil {
+		errs = append(errs, field.NotFound(fldPath.Key(annotationNameTrigger), patchName))
+	}
+	if value, ok := annotations[annotationNameTriggerDuration]; ok {
+		if _, err := parseTriggerDuration(value); err != nil {
+			errs = append(errs, field.Invalid(fldPath.Key(annotationNameTriggerDuration), value, err.Error()))
+		}
+	}
+	return errs
+}
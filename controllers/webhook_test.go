@@ -0,0 +1,590 @@
+/*
+Copyright 2021 Daisuke Taniwaki.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
+	"github.com/stretchr/testify/assert"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+)
+
+func TestWebhookValidate(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: one
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    strategicMergePatch:
+      spec:
+        maxReplicas: 15
+  - name: two
+    schedule: "0 22 * * *"
+    timezone: "Asia/Tokyo"
+    jsonPatch:
+    - op: replace
+      path: /spec/maxReplicas
+      value: 5
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	webhook := &CronHorizontalPodAutoscalerWebhook{}
+
+	// Valid patches.
+	err = webhook.ValidateCreate(ctx, cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	err = webhook.ValidateUpdate(ctx, cronhpa.ToCompatible(), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Invalid patches.
+	invalid := cronhpa.ToCompatible().DeepCopy()
+	invalid.Spec.ScheduledPatches[1].JSONPatch[0].Path = "/metadata/name"
+	err = webhook.ValidateCreate(ctx, invalid)
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+	err = webhook.ValidateUpdate(ctx, cronhpa.ToCompatible(), invalid)
+	if !assert.Error(t, err) {
+		t.FailNow()
+	}
+
+	// Legacy invalid object can get a finalizer, be triggered and be deleted.
+	legacy := invalid.DeepCopy()
+	finalized := legacy.DeepCopy()
+	finalized.Finalizers = []string{finalizerName}
+	err = webhook.ValidateUpdate(ctx, legacy, finalized)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	triggered := finalized.DeepCopy()
+	triggered.Annotations = map[string]string{annotationNameTrigger: "one"}
+	err = webhook.ValidateUpdate(ctx, finalized, triggered)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	triggered.Annotations = map[string]string{annotationNameTrigger: "unknown"}
+	err = webhook.ValidateUpdate(ctx, finalized, triggered)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+	deleted := finalized.DeepCopy()
+	deleted.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	deleted.Finalizers = nil
+	err = webhook.ValidateUpdate(ctx, finalized, deleted)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Exclusion calendar.
+	calendar := cronhpa.ToCompatible().DeepCopy()
+	calendar.Spec.ExclusionCalendar = &cronhpav1beta1.ExclusionCalendar{Dates: []string{"2021-11-23"}}
+	err = webhook.ValidateCreate(ctx, calendar)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	calendar.Spec.ExclusionCalendar.Dates = []string{"2021/11/23"}
+	err = webhook.ValidateCreate(ctx, calendar)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+}
+
+func TestWebhookValidateWindow(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: business
+    schedule: "0 9 * * mon-fri"
+    endSchedule: "0 18 * * mon-fri"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 5
+  - name: lunch
+    schedule: "0 12 * * *"
+    duration: 1h
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 8
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	webhook := &CronHorizontalPodAutoscalerWebhook{}
+
+	// Valid windows.
+	err = webhook.ValidateCreate(ctx, cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Both duration and end schedule.
+	invalid := cronhpa.ToCompatible().DeepCopy()
+	invalid.Spec.ScheduledPatches[0].Duration = invalid.Spec.ScheduledPatches[1].Duration
+	err = webhook.ValidateCreate(ctx, invalid)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+
+	// Non-positive duration.
+	invalid = cronhpa.ToCompatible().DeepCopy()
+	invalid.Spec.ScheduledPatches[1].Duration.Duration = 0
+	err = webhook.ValidateCreate(ctx, invalid)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+
+	// Invalid end schedule.
+	invalid = cronhpa.ToCompatible().DeepCopy()
+	invalid.Spec.ScheduledPatches[0].EndSchedule = "0 18 * *"
+	err = webhook.ValidateCreate(ctx, invalid)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+
+	// Dated patch.
+	startTime := metav1.NewTime(time.Date(2026, 11, 27, 8, 0, 0, 0, time.UTC))
+	endTime := metav1.NewTime(time.Date(2026, 11, 28, 2, 0, 0, 0, time.UTC))
+	dated := cronhpa.ToCompatible().DeepCopy()
+	dated.Spec.ScheduledPatches[1].Schedule = ""
+	dated.Spec.ScheduledPatches[1].Duration = nil
+	dated.Spec.ScheduledPatches[1].StartTime = &startTime
+	dated.Spec.ScheduledPatches[1].EndTime = &endTime
+	err = webhook.ValidateCreate(ctx, dated)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Dated patch with a schedule.
+	invalid = dated.DeepCopy()
+	invalid.Spec.ScheduledPatches[1].Schedule = "0 12 * * *"
+	err = webhook.ValidateCreate(ctx, invalid)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+
+	// Dated patch without an end time.
+	invalid = dated.DeepCopy()
+	invalid.Spec.ScheduledPatches[1].EndTime = nil
+	err = webhook.ValidateCreate(ctx, invalid)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+
+	// Dated patch ending before the start.
+	invalid = dated.DeepCopy()
+	invalid.Spec.ScheduledPatches[1].StartTime, invalid.Spec.ScheduledPatches[1].EndTime = &endTime, &startTime
+	err = webhook.ValidateCreate(ctx, invalid)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+
+	// Patch without a schedule.
+	invalid = cronhpa.ToCompatible().DeepCopy()
+	invalid.Spec.ScheduledPatches[0].Schedule = ""
+	err = webhook.ValidateCreate(ctx, invalid)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+}
+
+func TestWebhookValidateSchedulesAndHPAs(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+    timezone: "Asia/Tokyo"
+    patch:
+      minReplicas: 5
+  - name: nighttime
+    schedule: "@daily"
+    timezone: "Asia/Tokyo"
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	webhook := &CronHorizontalPodAutoscalerWebhook{}
+
+	err = webhook.ValidateCreate(ctx, cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	invalidMinReplicas := int32(20)
+	for name, modify := range map[string]func(*cronhpav1beta1.CronHorizontalPodAutoscaler){
+		"invalid schedule": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[0].Schedule = "0 25 * * *"
+		},
+		"schedule with seconds": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[0].Schedule = "0 0 8 * * *"
+		},
+		"unknown timezone": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[0].Timezone = "Asia/Nowhere"
+		},
+		"duplicate names": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[1].Name = "daytime"
+		},
+		"minReplicas > maxReplicas in a patch": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			minReplicas := intstr.FromInt(int(invalidMinReplicas))
+			c.Spec.ScheduledPatches[0].Patch.MinReplicas = &minReplicas
+		},
+		"minReplicas > maxReplicas in the template": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template.Spec.MinReplicas = &invalidMinReplicas
+		},
+		"no scale target": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template.Spec.ScaleTargetRef.Name = ""
+		},
+		"no template": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+		},
+		"both template and hpaRef": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.HPARef = &corev1.LocalObjectReference{Name: "nginx"}
+		},
+		"hpaRef without name": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.HPARef = &corev1.LocalObjectReference{}
+		},
+		"both template and hpaSelector": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.HPASelector = &metav1.LabelSelector{}
+		},
+		"replicas without scaleTarget": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			replicas := int32(3)
+			c.Spec.ScheduledPatches[1].Replicas = &replicas
+		},
+		"patch with scaleTarget": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.ScaleTarget = &cronhpav1beta1.ScaleTarget{ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "nginx"}}
+		},
+		"scaleTarget without name": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.ScheduledPatches[0].Patch = nil
+			c.Spec.ScaleTarget = &cronhpav1beta1.ScaleTarget{ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment"}}
+		},
+		"patch with scaledObjectRef": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.ScaledObjectRef = &corev1.LocalObjectReference{Name: "nginx"}
+		},
+		"scaledObjectRef without name": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.ScheduledPatches[0].Patch = nil
+			c.Spec.ScaledObjectRef = &corev1.LocalObjectReference{}
+		},
+		"scaledObjectPatch without scaledObjectRef": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[1].ScaledObjectPatch = &cronhpav1beta1.ScaledObjectPatch{}
+		},
+		"trigger patch without name and type": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.ScheduledPatches[0].Patch = nil
+			c.Spec.ScheduledPatches[0].ScaledObjectPatch = &cronhpav1beta1.ScaledObjectPatch{Triggers: []cronhpav1beta1.ScaledObjectTriggerPatch{{}}}
+			c.Spec.ScaledObjectRef = &corev1.LocalObjectReference{Name: "nginx"}
+		},
+		"trigger an unknown patch": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Annotations = map[string]string{annotationNameTrigger: "unknown"}
+		},
+		"invalid trigger duration": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Annotations = map[string]string{annotationNameTrigger: "daytime", annotationNameTriggerDuration: "-1h"}
+		},
+		"ramp without duration": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[0].Ramp = &cronhpav1beta1.Ramp{Steps: 3}
+		},
+		"ramp with hpaSelector": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.HPASelector = &metav1.LabelSelector{MatchLabels: map[string]string{"profile": "web"}}
+			c.Spec.ScheduledPatches[0].Ramp = &cronhpav1beta1.Ramp{Duration: metav1.Duration{Duration: 30 * time.Minute}, Steps: 3}
+		},
+		"scaleDownGuard with scaleTarget": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.ScheduledPatches[0].Patch = nil
+			c.Spec.ScheduledPatches[1].Patch = nil
+			c.Spec.ScaleTarget = &cronhpav1beta1.ScaleTarget{ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "nginx"}}
+			c.Spec.ScaleDownGuard = &cronhpav1beta1.ScaleDownGuard{}
+		},
+		"non-positive scaleDownGuard retryInterval": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScaleDownGuard = &cronhpav1beta1.ScaleDownGuard{RetryInterval: &metav1.Duration{}}
+		},
+		"invalid relative minReplicas": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			minReplicas := intstr.FromString("double")
+			c.Spec.ScheduledPatches[0].Patch.MinReplicas = &minReplicas
+		},
+		"invalid relative maxReplicas with hpaRef": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.HPARef = &corev1.LocalObjectReference{Name: "nginx"}
+			maxReplicas := intstr.FromString("-50%")
+			c.Spec.ScheduledPatches[0].Patch.MaxReplicas = &maxReplicas
+		},
+		"removeMetrics without the Merge mode": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[0].Patch.RemoveMetrics = []cronhpav1beta1.MetricIdentity{{Type: autoscalingv2.ResourceMetricSourceType, Name: "memory"}}
+		},
+		"removeMetrics without name": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[0].Patch.MetricsMergeMode = cronhpav1beta1.MetricsMergeModeMerge
+			c.Spec.ScheduledPatches[0].Patch.RemoveMetrics = []cronhpav1beta1.MetricIdentity{{Type: autoscalingv2.ResourceMetricSourceType}}
+		},
+		"negative startingDeadlineSeconds": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			startingDeadlineSeconds := int64(-1)
+			c.Spec.ScheduledPatches[0].StartingDeadlineSeconds = &startingDeadlineSeconds
+		},
+		"missedSchedulePolicy without startingDeadlineSeconds": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[0].MissedSchedulePolicy = cronhpav1beta1.MissedSchedulePolicySkipIfMissed
+		},
+		"negative leadTime": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.ScheduledPatches[0].LeadTime = &metav1.Duration{Duration: -10 * time.Minute}
+		},
+		"invalid strategicMergePatch with hpaRef": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.HPARef = &corev1.LocalObjectReference{Name: "nginx"}
+			c.Spec.ScheduledPatches[1].StrategicMergePatch = &runtime.RawExtension{Raw: []byte(`{"status":{"currentReplicas":3}}`)}
+		},
+		"strategicMergePatch to an invalid HPA with hpaRef": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.HPARef = &corev1.LocalObjectReference{Name: "nginx"}
+			c.Spec.ScheduledPatches[1].StrategicMergePatch = &runtime.RawExtension{Raw: []byte(`{"spec":{"maxReplicas":0}}`)}
+		},
+		"malformed strategicMergePatch with hpaSelector": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.HPASelector = &metav1.LabelSelector{MatchLabels: map[string]string{"profile": "web"}}
+			c.Spec.ScheduledPatches[1].StrategicMergePatch = &runtime.RawExtension{Raw: []byte(`{"spec":{"maxReplicas":"many"}}`)}
+		},
+		"invalid jsonPatch with hpaSelector": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.HPASelector = &metav1.LabelSelector{MatchLabels: map[string]string{"profile": "web"}}
+			c.Spec.ScheduledPatches[1].JSONPatch = []cronhpav1beta1.JSONPatchOperation{{Op: "replace", Path: "/metadata/name", Value: &apiextensionsv1.JSON{Raw: []byte(`"nginx"`)}}}
+		},
+		"invalid hpaSelector": func(c *cronhpav1beta1.CronHorizontalPodAutoscaler) {
+			c.Spec.Template = nil
+			c.Spec.HPASelector = &metav1.LabelSelector{MatchExpressions: []metav1.LabelSelectorRequirement{{Key: "profile", Operator: "Unknown"}}}
+		},
+	} {
+		invalid := cronhpa.ToCompatible().DeepCopy()
+		modify(invalid)
+		err = webhook.ValidateCreate(ctx, invalid)
+		if !assert.True(t, apierrors.IsInvalid(err), name) {
+			t.FailNow()
+		}
+	}
+
+	// Adopt an HPA.
+	adopted := cronhpa.ToCompatible().DeepCopy()
+	adopted.Spec.Template = nil
+	adopted.Spec.HPARef = &corev1.LocalObjectReference{Name: "nginx"}
+	err = webhook.ValidateCreate(ctx, adopted)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Select HPAs.
+	selected := cronhpa.ToCompatible().DeepCopy()
+	selected.Spec.Template = nil
+	selected.Spec.HPASelector = &metav1.LabelSelector{MatchLabels: map[string]string{"profile": "web"}}
+	err = webhook.ValidateCreate(ctx, selected)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Generic patches of the adopted HPAs.
+	for _, generic := range []*cronhpav1beta1.CronHorizontalPodAutoscaler{adopted.DeepCopy(), selected.DeepCopy()} {
+		generic.Spec.ScheduledPatches[1].StrategicMergePatch = &runtime.RawExtension{Raw: []byte(`{"spec":{"minReplicas":3}}`)}
+		generic.Spec.ScheduledPatches[1].JSONPatch = []cronhpav1beta1.JSONPatchOperation{{Op: "remove", Path: "/spec/behavior"}}
+		err = webhook.ValidateCreate(ctx, generic)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	// Scale a workload directly.
+	scaled := cronhpa.ToCompatible().DeepCopy()
+	scaled.Spec.Template = nil
+	scaled.Spec.ScaleTarget = &cronhpav1beta1.ScaleTarget{ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: "Deployment", Name: "nginx"}}
+	replicas := int32(3)
+	scaled.Spec.ScheduledPatches[0].Patch = nil
+	scaled.Spec.ScheduledPatches[0].Replicas = &replicas
+	err = webhook.ValidateCreate(ctx, scaled)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Trigger a patch manually.
+	triggered := cronhpa.ToCompatible().DeepCopy()
+	triggered.Annotations = map[string]string{annotationNameTrigger: "daytime", annotationNameTriggerDuration: "30m"}
+	err = webhook.ValidateUpdate(ctx, cronhpa.ToCompatible(), triggered)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// Patch a KEDA ScaledObject.
+	scaledObject := cronhpa.ToCompatible().DeepCopy()
+	scaledObject.Spec.Template = nil
+	scaledObject.Spec.ScaledObjectRef = &corev1.LocalObjectReference{Name: "nginx"}
+	scaledObject.Spec.ScheduledPatches[0].Patch = nil
+	scaledObject.Spec.ScheduledPatches[0].ScaledObjectPatch = &cronhpav1beta1.ScaledObjectPatch{
+		MinReplicaCount: &replicas,
+		Triggers:        []cronhpav1beta1.ScaledObjectTriggerPatch{{Type: "prometheus", Metadata: map[string]string{"threshold": "50"}}},
+	}
+	err = webhook.ValidateCreate(ctx, scaledObject)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+}
+
+func TestWebhookDefault(t *testing.T) {
+	ctx := context.TODO()
+
+	cronHPAManifest := `
+apiVersion: cron-hpa.dtaniwaki.github.com/v1beta1
+kind: CronHorizontalPodAutoscaler
+metadata:
+  name: cron-hpa-sample
+  namespace: default
+spec:
+  template:
+    spec:
+      scaleTargetRef:
+        apiVersion: apps/v1
+        kind: Deployment
+        name: cron-hpa-nginx
+      minReplicas: 1
+      maxReplicas: 10
+  scheduledPatches:
+  - name: daytime
+    schedule: "0 8 * * *"
+  - name: nighttime
+    schedule: "0 22 * * *"
+    timezone: "America/New_York"
+  - name: campaign
+    startTime: "2021-11-26T08:00:00+09:00"
+    endTime: "2021-11-27T02:00:00+09:00"
+`
+
+	cronhpa := &CronHorizontalPodAutoscaler{}
+	err := yaml.Unmarshal([]byte(cronHPAManifest), cronhpa.ToCompatible())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	// The default timezone of the controller.
+	webhook := &CronHorizontalPodAutoscalerWebhook{DefaultTimezone: "UTC"}
+	obj := cronhpa.ToCompatible().DeepCopy()
+	err = webhook.Default(ctx, obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "UTC", obj.Spec.ScheduledPatches[0].Timezone) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "America/New_York", obj.Spec.ScheduledPatches[1].Timezone) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "", obj.Spec.ScheduledPatches[2].Timezone) {
+		t.FailNow()
+	}
+
+	// The timezone of the spec.
+	obj = cronhpa.ToCompatible().DeepCopy()
+	obj.Spec.Timezone = "Asia/Tokyo"
+	err = webhook.Default(ctx, obj)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "Asia/Tokyo", obj.Spec.ScheduledPatches[0].Timezone) {
+		t.FailNow()
+	}
+	if !assert.Equal(t, "America/New_York", obj.Spec.ScheduledPatches[1].Timezone) {
+		t.FailNow()
+	}
+
+	// Invalid timezone of the spec.
+	obj.Spec.Timezone = "Asia/Nowhere"
+	err = webhook.ValidateCreate(ctx, obj)
+	if !assert.True(t, apierrors.IsInvalid(err)) {
+		t.FailNow()
+	}
+}
@@ -19,19 +19,26 @@ package main
 import (
 	"flag"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/scale"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	cronhpav1alpha1 "github.com/dtaniwaki/cron-hpa/api/v1alpha1"
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
 	"github.com/dtaniwaki/cron-hpa/controllers"
 	//+kubebuilder:scaffold:imports
 )
@@ -45,6 +52,7 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(cronhpav1alpha1.AddToScheme(scheme))
+	utilruntime.Must(cronhpav1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -52,11 +60,14 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var defaultTimezone string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&defaultTimezone, "default-timezone", "",
+		"The timezone of the scheduled patches without timezones. The local timezone of the controller is used if empty.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -65,6 +76,11 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if _, err := time.LoadLocation(defaultTimezone); err != nil {
+		setupLog.Error(err, "invalid default timezone")
+		os.Exit(1)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		MetricsBindAddress:     metricsAddr,
@@ -72,12 +88,32 @@ func main() {
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
 		LeaderElectionID:       "a46ac287.dtaniwaki.github.com",
+		// Read the ConfigMaps of the exclusion calendars from the API server not to cache all ConfigMaps in the cluster.
+		ClientDisableCacheFor: []client.Object{&corev1.ConfigMap{}},
 	})
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
 	}
 
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to create discovery client")
+		os.Exit(1)
+	}
+	hpaGroupVersion, err := controllers.DetectHPAGroupVersion(discoveryClient)
+	if err != nil {
+		setupLog.Error(err, "unable to detect HPA API version")
+		os.Exit(1)
+	}
+	setupLog.Info("detected HPA API version", "groupVersion", hpaGroupVersion.String())
+
+	scaleClient, err := scale.NewForConfig(mgr.GetConfig(), mgr.GetRESTMapper(), dynamic.LegacyAPIPathResolverFunc, scale.NewDiscoveryScaleKindResolver(discoveryClient))
+	if err != nil {
+		setupLog.Error(err, "unable to create scale client")
+		os.Exit(1)
+	}
+
 	cron := controllers.NewCron()
 	cron.Start()
 
@@ -86,13 +122,24 @@ func main() {
 	}()
 
 	if err = (&controllers.CronHorizontalPodAutoscalerReconciler{
-		Cron:     cron,
-		Client:   mgr.GetClient(),
-		Recorder: mgr.GetEventRecorderFor("cron-hpa-controller"),
+		Cron:            cron,
+		Client:          mgr.GetClient(),
+		Recorder:        mgr.GetEventRecorderFor("cron-hpa-controller"),
+		DefaultTimezone: defaultTimezone,
+		HPAGroupVersion: hpaGroupVersion,
+		ScaleClient:     scaleClient,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CronHorizontalPodAutoscaler")
 		os.Exit(1)
 	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&controllers.CronHorizontalPodAutoscalerWebhook{
+			DefaultTimezone: defaultTimezone,
+		}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CronHorizontalPodAutoscaler")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
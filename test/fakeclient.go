@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 
 	cronhpav1alpha1 "github.com/dtaniwaki/cron-hpa/api/v1alpha1"
+	cronhpav1beta1 "github.com/dtaniwaki/cron-hpa/api/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -13,7 +14,7 @@ import (
 
 func NewFakeClient(ctx context.Context) (client.Client, error) {
 	testEnv := &envtest.Environment{
-		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases"), filepath.Join("..", "test", "crd")},
 		ErrorIfCRDPathMissing: true,
 	}
 	cfg, err := testEnv.Start()
@@ -29,6 +30,10 @@ func NewFakeClient(ctx context.Context) (client.Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	err = cronhpav1beta1.AddToScheme(scheme)
+	if err != nil {
+		return nil, err
+	}
 	k8sClient, err := client.New(cfg, client.Options{Scheme: scheme})
 	if err != nil {
 		return nil, err